@@ -0,0 +1,200 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Flusher is the interface implemented by outputs that buffer logs.
+//
+// Flush should block until buffered logs are written or ctx is done.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// OverflowPolicy specifies the behavior of AsyncWriter when its queue is full.
+type OverflowPolicy int
+
+// Overflow policies.
+const (
+	// OverflowBlock blocks Write until the queue has room.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the log being written.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest log in the queue.
+	OverflowDropOldest
+)
+
+// AsyncWriter is an io.WriteCloser that writes data asynchronously
+// to the underlying writer by a background goroutine.
+//
+// Each Write is queued as a whole and passed to the underlying writer
+// by a single Write call.  Errors from the underlying writer are
+// reported by the next call of Write, Flush, or Close.
+type AsyncWriter struct {
+	w       io.Writer
+	size    int
+	policy  OverflowPolicy
+	dropped uint64
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    [][]byte
+	head     uint64 // sequence number of queue[0]
+	written  uint64 // sequence number next to the last written data
+	writing  bool
+	closed   bool
+	lastErr  error
+	progress chan struct{}
+	done     chan struct{}
+}
+
+// NewAsyncWriter constructs an AsyncWriter that queues up to size writes.
+// policy specifies the behavior when the queue is full.
+func NewAsyncWriter(w io.Writer, size int, policy OverflowPolicy) *AsyncWriter {
+	if size <= 0 {
+		panic("invalid queue size")
+	}
+	a := &AsyncWriter{
+		w:        w,
+		size:     size,
+		policy:   policy,
+		progress: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	go a.run()
+	return a
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	for {
+		a.mu.Lock()
+		for len(a.queue) == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if len(a.queue) == 0 {
+			a.mu.Unlock()
+			return
+		}
+		batch := a.queue
+		a.queue = nil
+		a.head += uint64(len(batch))
+		end := a.head
+		a.writing = true
+		a.cond.Broadcast()
+		a.mu.Unlock()
+
+		var err error
+		for _, p := range batch {
+			if _, e := a.w.Write(p); e != nil {
+				err = e
+			}
+		}
+
+		a.mu.Lock()
+		a.writing = false
+		a.written = end
+		if err != nil {
+			a.lastErr = err
+		}
+		close(a.progress)
+		a.progress = make(chan struct{})
+		a.mu.Unlock()
+	}
+}
+
+// takeError returns and clears the last error.
+// a.mu must be held.
+func (a *AsyncWriter) takeError() error {
+	err := a.lastErr
+	a.lastErr = nil
+	return err
+}
+
+// Write queues a copy of p.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return 0, os.ErrClosed
+	}
+
+	if len(a.queue) >= a.size {
+		switch a.policy {
+		case OverflowDropNewest:
+			atomic.AddUint64(&a.dropped, 1)
+			return len(p), a.takeError()
+		case OverflowDropOldest:
+			a.queue[0] = nil
+			a.queue = a.queue[1:]
+			a.head++
+			atomic.AddUint64(&a.dropped, 1)
+		default:
+			for len(a.queue) >= a.size && !a.closed {
+				a.cond.Wait()
+			}
+			if a.closed {
+				return 0, os.ErrClosed
+			}
+		}
+	}
+
+	a.queue = append(a.queue, append([]byte(nil), p...))
+	a.cond.Broadcast()
+	return len(p), a.takeError()
+}
+
+// Dropped returns the number of writes discarded due to the queue overflow.
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Flush waits until data queued before the call are written or ctx is done.
+func (a *AsyncWriter) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	target := a.head + uint64(len(a.queue))
+	for {
+		if a.written >= target || (a.head >= target && !a.writing) {
+			err := a.takeError()
+			a.mu.Unlock()
+			return err
+		}
+		ch := a.progress
+		a.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		a.mu.Lock()
+	}
+}
+
+// Close writes all queued data and stops the background goroutine.
+// The underlying writer is not closed.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return os.ErrClosed
+	}
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.takeError()
+}
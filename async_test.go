@@ -0,0 +1,153 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter blocks Write until the gate is opened.
+type gateWriter struct {
+	gate chan struct{}
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	t.Parallel()
+
+	gw := &gateWriter{gate: make(chan struct{})}
+	close(gw.gate)
+	w := NewAsyncWriter(gw, 10, OverflowBlock)
+
+	l := NewLogger()
+	l.SetOutput(w)
+	l.SetFormatter(testFormat{})
+	for i := 0; i < 100; i++ {
+		if err := l.Error("a", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := gw.String(); len(got) != 100 {
+		t.Errorf("length should be 100 but %d", len(got))
+	}
+	if w.Dropped() != 0 {
+		t.Error("nothing should be dropped")
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("Write after Close should fail: %v", err)
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		policy   OverflowPolicy
+		expected string
+	}{
+		{OverflowDropNewest, "0123"},
+		{OverflowDropOldest, "0789"},
+	}
+
+	for _, c := range cases {
+		gw := &gateWriter{gate: make(chan struct{})}
+		w := NewAsyncWriter(gw, 3, c.policy)
+
+		// "0" is taken by the background goroutine and blocks.
+		w.Write([]byte("0"))
+		time.Sleep(100 * time.Millisecond)
+		for _, s := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"} {
+			if _, err := w.Write([]byte(s)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if w.Dropped() != 6 {
+			t.Errorf("policy %d: dropped should be 6 but %d", c.policy, w.Dropped())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		if err := w.Flush(ctx); err != context.DeadlineExceeded {
+			t.Errorf("policy %d: Flush should time out: %v", c.policy, err)
+		}
+		cancel()
+
+		close(gw.gate)
+		if err := w.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := gw.String(); got != c.expected {
+			t.Errorf("policy %d: got %q, want %q", c.policy, got, c.expected)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// blockingCloser never completes Flush.
+type blockingCloser struct {
+	closed bool
+}
+
+func (w *blockingCloser) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *blockingCloser) Flush(ctx context.Context) error {
+	select {}
+}
+
+func (w *blockingCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestLoggerCloseWithoutFlush(t *testing.T) {
+	t.Parallel()
+
+	w := &blockingCloser{}
+	l := NewLogger()
+	l.SetOutput(w)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close should not call Flush of io.Closer")
+	}
+	if !w.closed {
+		t.Error("output should be closed")
+	}
+}
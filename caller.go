@@ -0,0 +1,126 @@
+package log
+
+import (
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// maxStackDepth is the maximum number of frames in a stack trace.
+const maxStackDepth = 64
+
+// pkgDir is the directory of the source files of this package.
+var pkgDir string
+
+func init() {
+	_, file, _, _ := runtime.Caller(0)
+	pkgDir = path.Dir(file)
+}
+
+// SetReportCaller enables or disables recording of the caller location.
+// If enabled, FnCaller ("file:line") and FnFunction fields are added
+// to logs.  The caller is the first function outside of this package,
+// log/slog, and the standard log package.
+//
+// Logs written by this package itself, such as summaries of logs
+// suppressed by SetSampler or SetDedupWindow, do not have caller
+// information nor stack traces.
+//
+// This setting is shared with loggers derived by With.
+func (l *Logger) SetReportCaller(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&l.reportCaller, v)
+}
+
+// ReportCaller returns true if the caller location is recorded.
+func (l *Logger) ReportCaller() bool {
+	return atomic.LoadInt32(&l.reportCaller) != 0
+}
+
+// SetStackThreshold sets the threshold to record stack traces.
+// Logs at or above level, that is, logs whose severity is less than
+// or equal to level, have FnStack field as well as FnCaller and
+// FnFunction fields.  Zero disables stack traces, which is the default.
+//
+// This setting is shared with loggers derived by With.
+func (l *Logger) SetStackThreshold(level int) {
+	atomic.StoreInt32(&l.stackThreshold, int32(level))
+}
+
+// StackThreshold returns the threshold to record stack traces.
+func (l *Logger) StackThreshold() int {
+	return int(atomic.LoadInt32(&l.stackThreshold))
+}
+
+// isInternalFrame returns true if f should be skipped to find the caller.
+func isInternalFrame(f runtime.Frame) bool {
+	if path.Dir(f.File) == pkgDir && !strings.HasSuffix(f.File, "_test.go") {
+		return true
+	}
+	return strings.HasPrefix(f.Function, "log.") || strings.HasPrefix(f.Function, "log/")
+}
+
+// shortPath returns the last directory and the file name of file.
+func shortPath(file string) string {
+	i := strings.LastIndexByte(file, '/')
+	if i == -1 {
+		return file
+	}
+	j := strings.LastIndexByte(file[:i], '/')
+	return file[j+1:]
+}
+
+// addCaller returns a copy of fields with caller information.
+// Existing fields are not overwritten.
+func addCaller(fields map[string]interface{}, withStack bool) map[string]interface{} {
+	var pcs [maxStackDepth]uintptr
+	// skip runtime.Callers and addCaller.
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var caller, function string
+	var stack []byte
+	found := false
+	for {
+		f, more := frames.Next()
+		if !found && !isInternalFrame(f) {
+			found = true
+			caller = shortPath(f.File) + ":" + strconv.Itoa(f.Line)
+			function = f.Function
+			if !withStack {
+				break
+			}
+		}
+		if found {
+			// the same format as runtime/debug.Stack.
+			stack = append(stack, f.Function...)
+			stack = append(stack, "\n\t"...)
+			stack = append(stack, f.File...)
+			stack = append(stack, ':')
+			stack = strconv.AppendInt(stack, int64(f.Line), 10)
+			stack = append(stack, '\n')
+		}
+		if !more {
+			break
+		}
+	}
+	if !found {
+		return fields
+	}
+
+	f := make(map[string]interface{}, len(fields)+3)
+	f[FnCaller] = caller
+	f[FnFunction] = function
+	if withStack {
+		f[FnStack] = strings.TrimSuffix(string(stack), "\n")
+	}
+	for k, v := range fields {
+		f[k] = v
+	}
+	return f
+}
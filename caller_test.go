@@ -0,0 +1,162 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	_log "log"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLine returns the line number of the caller.
+func testLine() string {
+	_, _, line, _ := runtime.Caller(1)
+	return "caller_test.go:" + strconv.Itoa(line+1)
+}
+
+func TestCaller(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+	l.SetReportCaller(true)
+	ctx := WithLogger(context.Background(), l)
+	stdlog := _log.New(l.Writer(LvInfo), "", 0)
+	slogger := slog.New(NewSlogHandler(l))
+
+	testCases := []struct {
+		name string
+		log  func() string
+	}{
+		{"Logger.Error", func() string {
+			line := testLine()
+			l.Error("hello", nil)
+			return line
+		}},
+		{"Logger.With", func() string {
+			line := testLine()
+			l.With(map[string]interface{}{"abc": 1}).Info("hello", nil)
+			return line
+		}},
+		{"InfoContext", func() string {
+			line := testLine()
+			InfoContext(ctx, "hello", nil)
+			return line
+		}},
+		{"log.Print", func() string {
+			line := testLine()
+			stdlog.Print("hello")
+			return line
+		}},
+		{"slog.Info", func() string {
+			line := testLine()
+			slogger.Info("hello")
+			return line
+		}},
+	}
+	for _, tc := range testCases {
+		buf.Reset()
+		line := tc.log()
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatal(tc.name, err)
+		}
+		if !strings.HasSuffix(record[FnCaller].(string), "/"+line) {
+			t.Errorf("%s: caller should be %s: %v", tc.name, line, record[FnCaller])
+		}
+		if !strings.HasPrefix(record[FnFunction].(string), "github.com/cybozu-go/log.TestCaller.func") {
+			t.Errorf("%s: unexpected function: %v", tc.name, record[FnFunction])
+		}
+		if _, ok := record[FnStack]; ok {
+			t.Errorf("%s: stack should not be recorded", tc.name)
+		}
+	}
+}
+
+func TestStackThreshold(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+	l.SetStackThreshold(LvError)
+
+	if err := l.Warn("hello", nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), FnCaller) {
+		t.Error("caller should not be recorded: " + buf.String())
+	}
+
+	buf.Reset()
+	if err := l.Error("hello", map[string]interface{}{FnFunction: "overridden"}); err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[FnFunction] != "overridden" {
+		t.Errorf("fields should take precedence: %v", record[FnFunction])
+	}
+	stack, _ := record[FnStack].(string)
+	if !strings.HasPrefix(stack, "github.com/cybozu-go/log.TestStackThreshold\n\t") {
+		t.Errorf("unexpected stack: %q", stack)
+	}
+	if !strings.Contains(stack, "testing.tRunner") {
+		t.Errorf("stack should contain callers: %q", stack)
+	}
+}
+
+func TestCallerInternal(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	l.SetReportCaller(true)
+	l.SetStackThreshold(LvError)
+	l.SetSampler(&Sampler{
+		Interval: 50 * time.Millisecond,
+		First:    1,
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := l.Error("hello", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var records []map[string]interface{}
+	for len(records) < 2 {
+		select {
+		case b := <-ch:
+			var record map[string]interface{}
+			if err := json.Unmarshal(b, &record); err != nil {
+				t.Fatal(err)
+			}
+			records = append(records, record)
+		case <-time.After(10 * time.Second):
+			t.Fatal("summary log is not output")
+		}
+	}
+
+	if caller, _ := records[0][FnCaller].(string); !strings.Contains(caller, "caller_test.go:") {
+		t.Errorf("unexpected caller: %v", records[0])
+	}
+	for _, k := range []string{FnCaller, FnFunction, FnStack} {
+		if _, ok := records[1][k]; ok {
+			t.Errorf("the summary should not have %s: %v", k, records[1])
+		}
+	}
+}
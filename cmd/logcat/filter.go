@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/log"
+)
+
+// fieldPredicate tests a field of a record.
+type fieldPredicate struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+// fieldPredicates implements flag.Value for repeated -field flags.
+type fieldPredicates []fieldPredicate
+
+func (ps *fieldPredicates) String() string {
+	s := make([]string, len(*ps))
+	for i, p := range *ps {
+		s[i] = p.key + p.op + p.value
+	}
+	return strings.Join(s, ",")
+}
+
+func (ps *fieldPredicates) Set(s string) error {
+	p, err := parseFieldPredicate(s)
+	if err != nil {
+		return err
+	}
+	*ps = append(*ps, p)
+	return nil
+}
+
+func parseFieldPredicate(s string) (fieldPredicate, error) {
+	i := strings.IndexAny(s, "=!~")
+	if i == -1 {
+		return fieldPredicate{key: s}, nil
+	}
+
+	p := fieldPredicate{key: s[:i]}
+	switch {
+	case strings.HasPrefix(s[i:], "!="):
+		p.op, p.value = "!=", s[i+2:]
+	case s[i] == '=':
+		p.op, p.value = "=", s[i+1:]
+	case s[i] == '~':
+		p.op, p.value = "~", s[i+1:]
+		re, err := regexp.Compile(p.value)
+		if err != nil {
+			return p, err
+		}
+		p.re = re
+	default:
+		return p, fmt.Errorf("invalid field predicate: %s", s)
+	}
+	if len(p.key) == 0 {
+		return p, fmt.Errorf("invalid field predicate: %s", s)
+	}
+	return p, nil
+}
+
+func (p fieldPredicate) match(r *log.Record) bool {
+	v, ok := recordValue(r, p.key)
+	switch p.op {
+	case "=":
+		return ok && v == p.value
+	case "!=":
+		return !ok || v != p.value
+	case "~":
+		return ok && p.re.MatchString(v)
+	}
+	return ok
+}
+
+// recordValue returns the value of key in r as a string.
+func recordValue(r *log.Record, key string) (string, bool) {
+	switch key {
+	case log.FnTopic:
+		return r.Topic, true
+	case log.FnLoggedAt:
+		return r.LoggedAt.UTC().Format(log.RFC3339Micro), true
+	case log.FnSeverity:
+		if name := log.LevelName(r.Severity); len(name) > 0 {
+			return name, true
+		}
+		return fmt.Sprint(r.Severity), true
+	case log.FnUtsname:
+		return r.Utsname, true
+	case log.FnMessage:
+		return r.Message, true
+	}
+
+	v, ok := r.Fields[key]
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case time.Time:
+		return v.UTC().Format(log.RFC3339Micro), true
+	case nil:
+		return "null", true
+	}
+	return fmt.Sprint(v), true
+}
+
+// filter selects records to be printed.
+type filter struct {
+	level  int
+	topics map[string]bool
+	since  time.Time
+	until  time.Time
+	fields []fieldPredicate
+}
+
+func newFilter(level, topics, since, until string, fields []fieldPredicate) (*filter, error) {
+	f := &filter{fields: fields}
+	if len(level) > 0 {
+		// resolve the level name in the same way as loggers do.
+		l := log.NewLogger()
+		if err := l.SetThresholdByName(level); err != nil {
+			return nil, err
+		}
+		f.level = l.Threshold()
+	}
+	if len(topics) > 0 {
+		f.topics = make(map[string]bool)
+		for _, t := range strings.Split(topics, ",") {
+			f.topics[strings.TrimSpace(t)] = true
+		}
+	}
+
+	var err error
+	f.since, err = parseTime(since)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -since: %w", err)
+	}
+	f.until, err = parseTime(until)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -until: %w", err)
+	}
+	return f, nil
+}
+
+// parseTime parses s as an RFC3339 time or a duration before now.
+func parseTime(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, errors.New("neither RFC3339 time nor duration: " + s)
+	}
+	return time.Now().Add(-d), nil
+}
+
+func (f *filter) match(r *log.Record) bool {
+	if f.level != 0 && r.Severity > f.level {
+		return false
+	}
+	if f.topics != nil && !f.topics[r.Topic] {
+		return false
+	}
+	if !f.since.IsZero() && r.LoggedAt.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !r.LoggedAt.Before(f.until) {
+		return false
+	}
+	for _, p := range f.fields {
+		if !p.match(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/log"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	loggedAt := time.Date(2001, 12, 3, 13, 45, 1, 0, time.UTC)
+	r := &log.Record{
+		Topic:    "app",
+		LoggedAt: loggedAt,
+		Severity: log.LvWarn,
+		Utsname:  "host1",
+		Message:  "hello world",
+		Fields: map[string]interface{}{
+			"status": int64(404),
+			"path":   "/index.html",
+		},
+	}
+
+	testCases := []struct {
+		level, topics, since, until string
+		fields                      []string
+		expected                    bool
+	}{
+		{"", "", "", "", nil, true},
+		{"warning", "", "", "", nil, true},
+		{"error", "", "", "", nil, false},
+		{"", "web,app", "", "", nil, true},
+		{"", "web", "", "", nil, false},
+		{"", "", "2001-12-03T13:45:01Z", "2001-12-03T13:45:02Z", nil, true},
+		{"", "", "2001-12-03T13:45:02Z", "", nil, false},
+		{"", "", "", "2001-12-03T13:45:01Z", nil, false},
+		{"", "", "1h", "", nil, false},
+		{"", "", "", "", []string{"status=404", "path~^/index"}, true},
+		{"", "", "", "", []string{"status!=404"}, false},
+		{"", "", "", "", []string{"message~world", "user!=alice"}, true},
+		{"", "", "", "", []string{"user"}, false},
+	}
+
+	for _, tc := range testCases {
+		var ps fieldPredicates
+		for _, s := range tc.fields {
+			if err := ps.Set(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := newFilter(tc.level, tc.topics, tc.since, tc.until, ps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.match(r) != tc.expected {
+			t.Errorf("unexpected result for %+v", tc)
+		}
+	}
+
+	if _, err := newFilter("verbose", "", "", "", nil); err == nil {
+		t.Error("unknown level should be rejected")
+	}
+	var ps fieldPredicates
+	if err := ps.Set("=abc"); err == nil {
+		t.Error("empty key should be rejected")
+	}
+	if err := ps.Set("a~("); err == nil {
+		t.Error("invalid regexp should be rejected")
+	}
+}
@@ -0,0 +1,150 @@
+// logcat reads logs produced by github.com/cybozu-go/log, filters them,
+// and prints them in a human-friendly format or converts them into
+// another format.
+//
+// Usage:
+//
+//	logcat [flags] [FILE...]
+//
+// Logs are read from stdin if no FILE is given or FILE is "-".
+// The input format is detected automatically unless -f is specified.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cybozu-go/log"
+)
+
+var (
+	flgFormat = flag.String("f", "auto", "input format: auto, plain, logfmt, json, or msgpack")
+	flgOutput = flag.String("o", "pretty", "output format: pretty, plain, logfmt, json, or msgpack")
+	flgColor  = flag.String("color", "auto", "colorize pretty output: auto, always, or never")
+	flgLevel  = flag.String("level", "", "show logs at or above this severity, e.g. warning")
+	flgTopic  = flag.String("topic", "", "show logs of these comma-separated topics")
+	flgSince  = flag.String("since", "", "show logs at or after this RFC3339 time or duration ago")
+	flgUntil  = flag.String("until", "", "show logs before this RFC3339 time or duration ago")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [FILE...]\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	var fieldFlags fieldPredicates
+	flag.Var(&fieldFlags, "field", "show logs whose field matches KEY=VALUE, KEY!=VALUE,\nKEY~REGEXP, or KEY (exists); can be repeated")
+	flag.Usage = usage
+	flag.Parse()
+
+	f, err := newFilter(*flgLevel, *flgTopic, *flgSince, *flgUntil, fieldFlags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logcat:", err)
+		os.Exit(2)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	p, err := newPrinter(*flgOutput, *flgColor, w)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logcat:", err)
+		os.Exit(2)
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	status := 0
+	for _, name := range files {
+		if err := catFile(name, f, p); err != nil {
+			fmt.Fprintf(os.Stderr, "logcat: %s: %v\n", name, err)
+			status = 1
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "logcat:", err)
+		status = 1
+	}
+	os.Exit(status)
+}
+
+func catFile(name string, f *filter, p *printer) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	br := bufio.NewReader(r)
+	format := *flgFormat
+	if format == "auto" {
+		format = detectFormat(br)
+	}
+	d, err := log.NewDecoder(format, br)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for {
+		rec, err := d.Decode()
+		switch {
+		case err == io.EOF:
+			return errors.Join(errs...)
+		case err == io.ErrUnexpectedEOF:
+			errs = append(errs, errors.New("truncated log at the end"))
+			return errors.Join(errs...)
+		case err != nil && format != "msgpack" &&
+			(errors.Is(err, log.ErrInvalidRecord) || errors.Is(err, log.ErrTooLarge)):
+			// skip malformed lines.
+			fmt.Fprintf(os.Stderr, "logcat: %s: %v\n", name, err)
+			continue
+		case err != nil:
+			// I/O errors, or no way to find the next msgpack record.
+			errs = append(errs, err)
+			return errors.Join(errs...)
+		}
+
+		if !f.match(rec) {
+			continue
+		}
+		b, err := p.format(rec)
+		if err != nil {
+			// skip records that cannot be formatted such as those
+			// with invalid keys.
+			fmt.Fprintf(os.Stderr, "logcat: %s: %q: %v\n", name, rec.Message, err)
+			continue
+		}
+		if _, err := p.w.Write(b); err != nil {
+			errs = append(errs, err)
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// detectFormat guesses the format of logs from the first bytes.
+func detectFormat(br *bufio.Reader) string {
+	b, _ := br.Peek(len("topic="))
+	switch {
+	case len(b) == 0:
+		return "plain"
+	case b[0] == '{':
+		return "json"
+	case b[0] == 0x93:
+		// fixarray of 3 elements: [topic, time, record]
+		return "msgpack"
+	case string(b) == "topic=":
+		return "logfmt"
+	}
+	return "plain"
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatFileSkip(t *testing.T) {
+	t.Parallel()
+
+	input := `{"topic":"app","logged_at":"2001-12-03T13:45:01.000000Z","severity":"error","utsname":"host1","message":"first"}
+{"topic":"app","logged_at":"2001-12-03T13:45:02.000000Z","severity":"error","utsname":"host1","message":"bad","Bad-Key":1}
+{"topic":"","logged_at":"2001-12-03T13:45:03.000000Z","severity":"error","utsname":"host1","message":"no topic"}
+`
+	name := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(name, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := newFilter("", "", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	p, err := newPrinter("json", "never", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := catFile(name, f, p); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+	if !strings.Contains(lines[0], `"message":"first"`) {
+		t.Errorf("unexpected output: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"topic":"-"`) || !strings.Contains(lines[1], `"message":"no topic"`) {
+		t.Errorf("unexpected output: %s", lines[1])
+	}
+}
+
+func TestCatFileReadError(t *testing.T) {
+	t.Parallel()
+
+	f, err := newFilter("", "", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newPrinter("json", "never", new(bytes.Buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reading a directory fails repeatedly.
+	done := make(chan error, 1)
+	go func() {
+		done <- catFile(t.TempDir(), f, p)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("catFile should fail")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("catFile should stop on read errors")
+	}
+}
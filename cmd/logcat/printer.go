@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cybozu-go/log"
+)
+
+// printer prints records by a formatter.
+type printer struct {
+	w   io.Writer
+	buf []byte
+
+	// newFormatter returns a formatter for utsname.
+	newFormatter func(utsname string) log.Formatter
+
+	// loggers and formatters are caches to keep topics and utsnames
+	// of records.
+	loggers    map[string]*log.Logger
+	formatters map[string]log.Formatter
+}
+
+func newPrinter(format, color string, w io.Writer) (*printer, error) {
+	p := &printer{
+		w:          w,
+		loggers:    make(map[string]*log.Logger),
+		formatters: make(map[string]log.Formatter),
+	}
+
+	switch format {
+	case "pretty":
+		f := log.NewConsoleFormat(os.Stdout)
+		switch color {
+		case "always":
+			f.Color = true
+		case "never":
+			f.Color = false
+		case "auto":
+		default:
+			return nil, fmt.Errorf("invalid -color: %s", color)
+		}
+		p.newFormatter = func(string) log.Formatter { return f }
+	case "plain":
+		p.newFormatter = func(utsname string) log.Formatter { return log.PlainFormat{Utsname: utsname} }
+	case "logfmt":
+		p.newFormatter = func(utsname string) log.Formatter { return log.Logfmt{Utsname: utsname} }
+	case "json":
+		p.newFormatter = func(utsname string) log.Formatter { return log.JSONFormat{Utsname: utsname} }
+	case "msgpack":
+		p.newFormatter = func(utsname string) log.Formatter { return log.MsgPack{Utsname: utsname} }
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+	return p, nil
+}
+
+// emptyTopic is used for records without topics as loggers require one.
+const emptyTopic = "-"
+
+// format formats r.  The returned slice is valid until the next call.
+func (p *printer) format(r *log.Record) ([]byte, error) {
+	topic := r.Topic
+	if len(topic) == 0 {
+		topic = emptyTopic
+	}
+	l, ok := p.loggers[topic]
+	if !ok {
+		l = log.NewLogger()
+		l.SetTopic(topic)
+		p.loggers[topic] = l
+	}
+	f, ok := p.formatters[r.Utsname]
+	if !ok {
+		f = p.newFormatter(r.Utsname)
+		p.formatters[r.Utsname] = f
+	}
+
+	b, err := f.Format(p.buf[:0], l, r.LoggedAt, r.Severity, r.Message, r.Fields)
+	if err != nil {
+		return nil, err
+	}
+	p.buf = b
+	return b, nil
+}
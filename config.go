@@ -0,0 +1,149 @@
+package log
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// defaultReopenSignal is the default signal to reopen log files.
+const defaultReopenSignal = "SIGUSR1"
+
+// LevelValue is a level name that implements flag.Value and
+// encoding.TextUnmarshaler.  Set and UnmarshalText accept names
+// accepted by Logger.SetThresholdByName.
+type LevelValue string
+
+// String implements flag.Value.
+func (v *LevelValue) String() string {
+	return string(*v)
+}
+
+// Set implements flag.Value.
+func (v *LevelValue) Set(s string) error {
+	if _, err := levelByName(s); err != nil {
+		return err
+	}
+	*v = LevelValue(s)
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *LevelValue) UnmarshalText(text []byte) error {
+	return v.Set(string(text))
+}
+
+// FormatValue is a formatter name that implements flag.Value and
+// encoding.TextUnmarshaler.  Set and UnmarshalText accept names
+// registered by RegisterFormatter.
+type FormatValue string
+
+// String implements flag.Value.
+func (v *FormatValue) String() string {
+	return string(*v)
+}
+
+// Set implements flag.Value.
+func (v *FormatValue) Set(s string) error {
+	if _, err := newFormatter(s, ""); err != nil {
+		return err
+	}
+	*v = FormatValue(s)
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *FormatValue) UnmarshalText(text []byte) error {
+	return v.Set(string(text))
+}
+
+// Config is a set of logger settings that can be bound to command-line
+// flags by AddFlags or decoded from JSON or YAML.  Empty fields are
+// not applied.
+type Config struct {
+	// Level is the threshold name such as "info".
+	Level LevelValue `json:"level,omitempty" yaml:"level,omitempty"`
+
+	// Format is the formatter name such as "json".
+	Format FormatValue `json:"format,omitempty" yaml:"format,omitempty"`
+
+	// File is the path of the log file.  If empty, the output is
+	// not changed.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+
+	// ReopenSignal is the signal name to reopen File.
+	// If empty, "SIGUSR1" is used except on Windows.
+	ReopenSignal string `json:"reopen_signal,omitempty" yaml:"reopen_signal,omitempty"`
+
+	// Topic is the topic.
+	Topic string `json:"topic,omitempty" yaml:"topic,omitempty"`
+}
+
+// AddFlags adds -loglevel, -logformat, -logfile, and -logtopic flags
+// to fs that set c.  Current values of c are used as defaults.
+func (c *Config) AddFlags(fs *flag.FlagSet) {
+	fs.Var(&c.Level, "loglevel", "threshold of logs: critical, error, warning, info, or debug")
+	fs.Var(&c.Format, "logformat", "format of logs: "+strings.Join(FormatterNames(), ", "))
+	fs.StringVar(&c.File, "logfile", c.File, "log file path; logs go to stderr if empty")
+	fs.StringVar(&c.Topic, "logtopic", c.Topic, "topic of logs")
+}
+
+// Apply applies c to l.  Unknown level or format names result in errors
+// without modifying l.  The log file is opened with NewFileReopener.
+func (c *Config) Apply(l *Logger) error {
+	var level int
+	if len(c.Level) > 0 {
+		lv, err := levelByName(string(c.Level))
+		if err != nil {
+			return err
+		}
+		level = lv
+	}
+
+	var formatter Formatter
+	if len(c.Format) > 0 {
+		f, err := newFormatter(string(c.Format), "")
+		if err != nil {
+			return err
+		}
+		formatter = f
+	}
+
+	var output io.Writer
+	if len(c.File) > 0 {
+		var sig os.Signal
+		name := c.ReopenSignal
+		if len(name) == 0 && runtime.GOOS != "windows" {
+			name = defaultReopenSignal
+		}
+		if len(name) > 0 {
+			s, err := parseReopenSignal(name)
+			if err != nil {
+				return err
+			}
+			sig = s
+		}
+		w, err := openOutputFile(c.File, sig)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		output = w
+	}
+
+	if level != 0 {
+		l.SetThreshold(level)
+	}
+	if formatter != nil {
+		l.SetFormatter(outputFormatter(string(c.Format), formatter, l, output))
+	}
+	if output != nil {
+		l.SetOutput(output)
+	}
+	if len(c.Topic) > 0 {
+		l.SetTopic(c.Topic)
+	}
+	return nil
+}
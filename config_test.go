@@ -0,0 +1,82 @@
+package log
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFlags(t *testing.T) {
+	t.Parallel()
+
+	logfile := filepath.Join(t.TempDir(), "test.log")
+
+	var c Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	c.AddFlags(fs)
+	err := fs.Parse([]string{"-loglevel", "debug", "-logformat", "logfmt", "-logfile", logfile, "-logtopic", "cfgtest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLogger()
+	if err := c.Apply(l); err != nil {
+		t.Fatal(err)
+	}
+	if l.Threshold() != LvDebug || l.Formatter().String() != "logfmt" || l.Topic() != "cfgtest" {
+		t.Errorf("unexpected logger: %d %s %s", l.Threshold(), l.Formatter(), l.Topic())
+	}
+	if err := l.Debug("hello", nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `topic=cfgtest`) {
+		t.Errorf("unexpected log: %s", data)
+	}
+
+	for _, args := range [][]string{{"-loglevel", "verbose"}, {"-logformat", "xml"}} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		new(Config).AddFlags(fs)
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("%v should be rejected", args)
+		}
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	t.Parallel()
+
+	var c Config
+	if err := json.Unmarshal([]byte(`{"level":"warning","format":"json"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	l := NewLogger()
+	if err := c.Apply(l); err != nil {
+		t.Fatal(err)
+	}
+	if l.Threshold() != LvWarn || l.Formatter().String() != "json" {
+		t.Errorf("unexpected logger: %d %s", l.Threshold(), l.Formatter())
+	}
+
+	if err := json.Unmarshal([]byte(`{"level":"verbose"}`), &c); err == nil {
+		t.Error("unknown level should be rejected")
+	}
+
+	// values set directly are validated by Apply.
+	c = Config{Level: "info", Format: "xml"}
+	if err := c.Apply(l); err == nil {
+		t.Error("unknown format should be rejected")
+	}
+	if l.Threshold() != LvWarn {
+		t.Error("logger should not be modified")
+	}
+}
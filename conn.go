@@ -0,0 +1,79 @@
+package log
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// dialTimeout is the timeout to connect to log servers.
+	dialTimeout = 10 * time.Second
+)
+
+// netWriter is an io.WriteCloser that writes data to a network connection.
+// If writing fails, it reconnects and retries once.
+type netWriter struct {
+	network string
+	addr    string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// dialNetWriter connects to addr over network and returns a netWriter.
+func dialNetWriter(network, addr string) (*netWriter, error) {
+	w := &netWriter{
+		network: network,
+		addr:    addr,
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect (re)connects to the server.  w.mu must be held.
+func (w *netWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	c, err := net.DialTimeout(w.network, w.addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+	w.conn = c
+	return nil
+}
+
+// Write writes p by a single Write call of the connection.
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		n, err := w.conn.Write(p)
+		if err == nil {
+			return n, nil
+		}
+	}
+
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	return w.conn.Write(p)
+}
+
+// Close closes the connection.
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
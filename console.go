@@ -0,0 +1,339 @@
+package log
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// consoleMessageWidth is the width to align fields after messages.
+	consoleMessageWidth = 40
+
+	// consoleTimeFormat is the time format of ConsoleFormat.
+	consoleTimeFormat = "2006-01-02 15:04:05.000000"
+
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiGray    = "\x1b[90m"
+)
+
+// ConsoleFormat implements Formatter for human-friendly output
+// on consoles.  It is intended for development.
+//
+// A console log message looks like:
+//
+//	2006-01-02 15:04:05.000000 INFO  topic: message    key1=value1 key2=value2
+//	    map_field:
+//	        key=value
+//
+// Messages are padded so that fields are aligned.  Maps in fields are
+// printed over multiple lines after the message line.  Hostnames are
+// not printed.
+type ConsoleFormat struct {
+	// Color enables ANSI color escape sequences.
+	Color bool
+
+	// LocalTime prints times in the local time zone instead of UTC.
+	LocalTime bool
+}
+
+// NewConsoleFormat returns a ConsoleFormat that prints local times.
+// Color is enabled if w is a terminal, NO_COLOR environment variable
+// is empty, and TERM environment variable is not "dumb".
+//
+// "console" formatter registered by default prints local times without
+// colors because the output is not known when the formatter is created.
+// ConfigureFromEnv and Config.Apply use NewConsoleFormat with the output
+// of the logger instead.
+func NewConsoleFormat(w io.Writer) ConsoleFormat {
+	return ConsoleFormat{
+		Color:     useColor(w),
+		LocalTime: true,
+	}
+}
+
+// outputFormatter returns NewConsoleFormat for output if name is
+// "console", or f otherwise.  If output is nil, the current output
+// of l is used.
+func outputFormatter(name string, f Formatter, l *Logger, output io.Writer) Formatter {
+	if name != "console" {
+		return f
+	}
+	if output == nil {
+		l.mu.Lock()
+		output = l.output
+		l.mu.Unlock()
+	}
+	return NewConsoleFormat(output)
+}
+
+// useColor returns true if w is a terminal that accepts colors.
+// https://no-color.org/
+func useColor(w io.Writer) bool {
+	if len(os.Getenv("NO_COLOR")) > 0 || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// String returns "console".
+func (f ConsoleFormat) String() string {
+	return "console"
+}
+
+func (f ConsoleFormat) paint(buf []byte, color string, s string) []byte {
+	if !f.Color {
+		return append(buf, s...)
+	}
+	buf = append(buf, color...)
+	buf = append(buf, s...)
+	return append(buf, ansiReset...)
+}
+
+func (f ConsoleFormat) time(t time.Time) time.Time {
+	if f.LocalTime {
+		return t.Local()
+	}
+	return t.UTC()
+}
+
+func consoleSeverity(severity int) (string, string) {
+	switch severity {
+	case LvCritical:
+		return "CRIT", ansiBold + ansiMagenta
+	case LvError:
+		return "ERROR", ansiRed
+	case LvWarn:
+		return "WARN", ansiYellow
+	case LvInfo:
+		return "INFO", ansiGreen
+	case LvDebug:
+		return "DEBUG", ansiGray
+	}
+	return strconv.Itoa(severity), ""
+}
+
+// Format implements Formatter.Format.
+func (f ConsoleFormat) Format(buf []byte, l *Logger, t time.Time, severity int,
+	msg string, fields map[string]interface{}) ([]byte, error) {
+	keys, values, err := mergeFields(l, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = f.paint(buf, ansiGray, f.time(t).Format(consoleTimeFormat))
+	buf = append(buf, ' ')
+	name, color := consoleSeverity(severity)
+	buf = f.paint(buf, color, name)
+	for i := len(name); i < len("ERROR"); i++ {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, ' ')
+	buf = f.paint(buf, ansiBold, l.Topic())
+	buf = append(buf, ": "...)
+	msg = strings.ToValidUTF8(msg, string(utf8.RuneError))
+	buf = append(buf, msg...)
+
+	var nested []int
+	padded := false
+	for i, k := range keys {
+		if isConsoleNested(values[i]) {
+			nested = append(nested, i)
+			continue
+		}
+		if !padded {
+			for n := utf8.RuneCountInString(msg); n < consoleMessageWidth; n++ {
+				buf = append(buf, ' ')
+			}
+			padded = true
+		}
+		buf = append(buf, ' ')
+		buf = f.paint(buf, ansiCyan, k)
+		buf = append(buf, '=')
+		buf, err = f.appendValue(buf, values[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	buf = append(buf, '\n')
+
+	for _, i := range nested {
+		buf, err = f.appendNested(buf, 1, keys[i], values[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// isConsoleNested returns true if v is a string-keyed map.
+func isConsoleNested(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.(encoding.TextMarshaler); ok {
+		return false
+	}
+	typ := reflect.TypeOf(v)
+	return typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String
+}
+
+// appendNested appends a map over multiple lines indented by depth.
+func (f ConsoleFormat) appendNested(buf []byte, depth int, key string, v interface{}) ([]byte, error) {
+	var err error
+	for i := 0; i < depth; i++ {
+		buf = append(buf, "    "...)
+	}
+	buf = f.paint(buf, ansiCyan, key)
+	buf = append(buf, ":\n"...)
+
+	value := reflect.ValueOf(v)
+	for _, k := range sortedMapKeys(value) {
+		e := value.MapIndex(reflect.ValueOf(k).Convert(value.Type().Key())).Interface()
+		if isConsoleNested(e) {
+			buf, err = f.appendNested(buf, depth+1, k, e)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for i := 0; i <= depth; i++ {
+			buf = append(buf, "    "...)
+		}
+		buf = f.paint(buf, ansiCyan, k)
+		buf = append(buf, '=')
+		buf, err = f.appendValue(buf, e)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, '\n')
+	}
+	return buf, nil
+}
+
+// sortedMapKeys returns the sorted keys of a string-keyed map.
+func sortedMapKeys(value reflect.Value) []string {
+	keys := make([]string, 0, value.Len())
+	for iter := value.MapRange(); iter.Next(); {
+		keys = append(keys, iter.Key().String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appendConsoleString appends s, quoting it if necessary.
+func appendConsoleString(buf []byte, s string) []byte {
+	if len(s) == 0 || strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, func(r rune) bool {
+		return r < ' ' || r == utf8.RuneError || r == 0x7f
+	}) != -1 {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func (f ConsoleFormat) appendValue(buf []byte, v interface{}) ([]byte, error) {
+	var err error
+
+	switch t := v.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case bool:
+		return strconv.AppendBool(buf, t), nil
+	case time.Time:
+		return f.time(t).AppendFormat(buf, RFC3339Micro), nil
+	case int:
+		return strconv.AppendInt(buf, int64(t), 10), nil
+	case int8:
+		return strconv.AppendInt(buf, int64(t), 10), nil
+	case int16:
+		return strconv.AppendInt(buf, int64(t), 10), nil
+	case int32:
+		return strconv.AppendInt(buf, int64(t), 10), nil
+	case int64:
+		return strconv.AppendInt(buf, t, 10), nil
+	case uint:
+		return strconv.AppendUint(buf, uint64(t), 10), nil
+	case uint8:
+		return strconv.AppendUint(buf, uint64(t), 10), nil
+	case uint16:
+		return strconv.AppendUint(buf, uint64(t), 10), nil
+	case uint32:
+		return strconv.AppendUint(buf, uint64(t), 10), nil
+	case uint64:
+		return strconv.AppendUint(buf, t, 10), nil
+	case float32:
+		return strconv.AppendFloat(buf, float64(t), 'f', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat(buf, t, 'f', -1, 64), nil
+	case string:
+		return appendConsoleString(buf, t), nil
+	case encoding.TextMarshaler:
+		s, err := t.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return appendConsoleString(buf, string(s)), nil
+	case error:
+		return appendConsoleString(buf, t.Error()), nil
+	}
+
+	value := reflect.ValueOf(v)
+	kind := value.Kind()
+
+	// slices and arrays, excluding []byte handled by %v below.
+	if (kind == reflect.Slice || kind == reflect.Array) && value.Type().Elem().Kind() != reflect.Uint8 {
+		buf = append(buf, '[')
+		for i := 0; i < value.Len(); i++ {
+			if i > 0 {
+				buf = append(buf, ' ')
+			}
+			buf, err = f.appendValue(buf, value.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	}
+
+	// maps in slices are printed inline.
+	if isConsoleNested(v) {
+		buf = append(buf, '{')
+		for i, k := range sortedMapKeys(value) {
+			if i > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = appendConsoleString(buf, k)
+			buf = append(buf, '=')
+			e := value.MapIndex(reflect.ValueOf(k).Convert(value.Type().Key())).Interface()
+			buf, err = f.appendValue(buf, e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+	}
+
+	// other types are just formatted as string with "%v".
+	return appendConsoleString(buf, fmt.Sprintf("%v", v)), nil
+}
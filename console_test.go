@@ -0,0 +1,136 @@
+package log
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleFormat(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("console")
+	l.SetDefaults(map[string]interface{}{"env": "dev"})
+	loggedAt := time.Date(2001, 12, 3, 13, 45, 1, 123456000, time.UTC)
+
+	f := ConsoleFormat{}
+	b, err := f.Format(nil, l, loggedAt, LvWarn, "hello", map[string]interface{}{
+		"count": 3,
+		"path":  "/a b",
+		"tags":  []string{"x", "y"},
+		"req": map[string]interface{}{
+			"method": "GET",
+			"header": map[string]string{"host": "example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2001-12-03 13:45:01.123456 WARN  console: hello" + strings.Repeat(" ", 35) +
+		` count=3 env=dev path="/a b" tags=[x y]
+    req:
+        header:
+            host=example.com
+        method=GET
+`
+	if string(b) != expected {
+		t.Errorf("unexpected output:\n%q\n%q", b, expected)
+	}
+
+	b, err = f.Format(nil, l, loggedAt, 99, "no fields", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "2001-12-03 13:45:01.123456 99    console: no fields"+strings.Repeat(" ", 31)+" env=dev\n" {
+		t.Errorf("unexpected output: %q", b)
+	}
+
+	f.Color = true
+	b, err = f.Format(nil, l, loggedAt, LvError, "colored", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), ansiRed+"ERROR"+ansiReset) {
+		t.Errorf("severity is not colored: %q", b)
+	}
+
+	if _, err := f.Format(nil, l, loggedAt, LvInfo, "", map[string]interface{}{"Bad": 1}); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestNewConsoleFormat(t *testing.T) {
+	f := NewConsoleFormat(&strings.Builder{})
+	if f.Color || !f.LocalTime {
+		t.Errorf("unexpected format: %+v", f)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no terminal")
+	}
+	defer tty.Close()
+
+	t.Setenv("TERM", "xterm")
+	t.Setenv("NO_COLOR", "")
+	if !NewConsoleFormat(tty).Color {
+		t.Error("color should be enabled for terminals")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if NewConsoleFormat(tty).Color {
+		t.Error("color should be disabled by NO_COLOR")
+	}
+}
+
+func TestConsoleFormatRegistered(t *testing.T) {
+	t.Parallel()
+
+	f, err := FormatterByName("console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the output is unknown, so colors are disabled.
+	if cf := f.(ConsoleFormat); cf.Color || !cf.LocalTime {
+		t.Errorf("unexpected format: %+v", cf)
+	}
+}
+
+func TestConsoleFormatOutput(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	t.Setenv("NO_COLOR", "")
+
+	l := NewLogger()
+	l.SetOutput(io.Discard)
+	if err := (&Config{Format: "console"}).Apply(l); err != nil {
+		t.Fatal(err)
+	}
+	if f := l.Formatter().(ConsoleFormat); f.Color || !f.LocalTime {
+		t.Errorf("color should be disabled for non-terminals: %+v", f)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no terminal")
+	}
+	defer tty.Close()
+
+	l.SetOutput(tty)
+	if err := (&Config{Format: "console"}).Apply(l); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Formatter().(ConsoleFormat).Color {
+		t.Error("color should be enabled for terminals by Config")
+	}
+
+	t.Setenv(EnvLogFormat, "console")
+	l.SetFormatter(PlainFormat{})
+	if err := ConfigureFromEnv(l); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Formatter().(ConsoleFormat).Color {
+		t.Error("color should be enabled for terminals by ConfigureFromEnv")
+	}
+}
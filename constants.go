@@ -29,6 +29,13 @@ const (
 	FnServiceSet     = "serviceset"
 	FnStartAt        = "start_at"
 	FnError          = "error"
+	FnCaller         = "caller"
+	FnFunction       = "function"
+	FnStack          = "stack"
+	FnSuppressed     = "suppressed"
+	FnRepeated       = "repeated"
+	FnFirstAt        = "first_at"
+	FnLastAt         = "last_at"
 )
 
 // Severities a.k.a log levels.
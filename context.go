@@ -0,0 +1,127 @@
+package log
+
+import (
+	"context"
+	"time"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	fieldsKey
+)
+
+// WithLogger returns a new context carrying l.
+// The logger can be retrieved by FromContext.
+func WithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger stored in ctx by WithLogger.
+// If ctx has no logger, the default logger is returned.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey).(*Logger); ok {
+			return l
+		}
+	}
+	return defaultLogger
+}
+
+// WithFields returns a new context carrying fields in addition to
+// those already stored in ctx.  On conflict, fields take precedence.
+//
+// Fields in a context are added to logs by Logger.LogContext and
+// other *Context functions.  If fields contain a key that is not valid
+// in the sense of IsValidKey, logging with the context returns
+// ErrInvalidKey.
+func WithFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	old := contextFieldsFrom(ctx)
+	f := make(map[string]interface{}, len(old.fields)+len(fields))
+	for k, v := range old.fields {
+		f[k] = v
+	}
+	invalidKey := old.invalidKey
+	for k, v := range fields {
+		if !IsValidKey(k) {
+			invalidKey = true
+		}
+		f[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey, &contextFields{fields: f, invalidKey: invalidKey})
+}
+
+// contextFields is stored in contexts by WithFields.
+// Keys are validated at WithFields to avoid validation for each log.
+type contextFields struct {
+	fields     map[string]interface{}
+	invalidKey bool
+}
+
+func contextFieldsFrom(ctx context.Context) *contextFields {
+	if ctx != nil {
+		if f, ok := ctx.Value(fieldsKey).(*contextFields); ok {
+			return f
+		}
+	}
+	return &contextFields{}
+}
+
+// FieldsFromContext returns fields stored in ctx by WithFields.
+// The returned map must not be modified.
+func FieldsFromContext(ctx context.Context) map[string]interface{} {
+	return contextFieldsFrom(ctx).fields
+}
+
+// WithRequestID returns a new context carrying id as FnRequestID field.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, map[string]interface{}{
+		FnRequestID: id,
+	})
+}
+
+// RequestIDFromContext returns FnRequestID field stored in ctx.
+// An empty string is returned if ctx has no request ID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := FieldsFromContext(ctx)[FnRequestID].(string)
+	return id
+}
+
+// LogContext outputs a log message with additional fields and
+// fields stored in ctx by WithFields.  fields take precedence over
+// fields in ctx.  fields can be nil.
+func (l *Logger) LogContext(ctx context.Context, severity int, msg string,
+	fields map[string]interface{}) error {
+	return l.logContextAt(ctx, time.Time{}, severity, msg, fields)
+}
+
+// logContextAt is LogContext for a log logged at t.  If t is zero,
+// the current time is used.
+func (l *Logger) logContextAt(ctx context.Context, t time.Time, severity int, msg string,
+	fields map[string]interface{}) error {
+	if !l.Enabled(severity) {
+		return nil
+	}
+
+	c := contextFieldsFrom(ctx)
+	if c.invalidKey {
+		return ErrInvalidKey
+	}
+	cf := c.fields
+	if len(cf) == 0 {
+		return l.logAt(t, severity, msg, fields)
+	}
+	if len(fields) == 0 {
+		return l.logAt(t, severity, msg, cf)
+	}
+
+	f := make(map[string]interface{}, len(cf)+len(fields))
+	for k, v := range cf {
+		f[k] = v
+	}
+	for k, v := range fields {
+		f[k] = v
+	}
+	return l.logAt(t, severity, msg, f)
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	if FromContext(ctx) != DefaultLogger() {
+		t.Error("FromContext should return the default logger")
+	}
+	if RequestIDFromContext(ctx) != "" {
+		t.Error("request ID should be empty")
+	}
+
+	l := NewLogger()
+	l.SetTopic("ctx")
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(PlainFormat{"localhost"})
+
+	ctx = WithLogger(ctx, l)
+	if FromContext(ctx) != l {
+		t.Error("FromContext should return the stored logger")
+	}
+
+	ctx = WithRequestID(ctx, "1234")
+	ctx2 := WithFields(ctx, map[string]interface{}{
+		"abc": 1,
+		"def": 2,
+	})
+	if RequestIDFromContext(ctx2) != "1234" {
+		t.Error("request ID should be inherited")
+	}
+	if len(FieldsFromContext(ctx)) != 1 {
+		t.Error("parent context should not be modified")
+	}
+
+	if err := InfoContext(ctx2, "hello", map[string]interface{}{"def": 3}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, ` ctx info: "hello" abc=1 def=3 request_id="1234"`) {
+		t.Error("unexpected log: " + s)
+	}
+
+	buf.Reset()
+	if err := DebugContext(ctx2, "ignored", nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Error("debug log should be ignored")
+	}
+
+	buf.Reset()
+	if err := l.LogContext(ctx, LvError, "plain", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), ` ctx error: "plain" request_id="1234"`) {
+		t.Error("unexpected log: " + buf.String())
+	}
+
+	bad := WithFields(ctx, map[string]interface{}{FnTopic: "bad"})
+	if err := l.LogContext(bad, LvError, "bad", nil); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+
+	// MsgPack does not validate keys by itself.
+	l.SetFormatter(MsgPack{})
+	buf.Reset()
+	bad = WithFields(ctx, map[string]interface{}{"Bad-Key": 1})
+	if err := l.LogContext(WithFields(bad, nil), LvError, "bad", nil); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("log with invalid keys should not be written")
+	}
+}
@@ -0,0 +1,534 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a log record decoded by Decoder.
+type Record struct {
+	Topic    string
+	LoggedAt time.Time
+	Severity int
+	Utsname  string
+	Message  string
+
+	// Fields has fields other than the above.  Values are nil, bool,
+	// int64, uint64, float64, string, time.Time, []interface{}, or
+	// map[string]interface{}.  MsgPack binaries are decoded as []byte.
+	//
+	// As formatters lose type information, the types may differ
+	// from the original ones.  For example, time.Time values in fields
+	// are decoded as strings from JSON, and slices are decoded as
+	// strings from PlainFormat output.
+	Fields map[string]interface{}
+}
+
+// Decoder decodes log records from a stream.
+type Decoder interface {
+	// Decode returns the next record.  io.EOF is returned when
+	// the stream ends at a record boundary.
+	//
+	// If the stream ends in the middle of a record, io.ErrUnexpectedEOF
+	// is returned.  The partial record is retained so that Decode can
+	// be called again after more data is appended, e.g. when following
+	// a growing file.
+	//
+	// Decoders for line-based formats skip empty lines.  If a line is
+	// malformed, an error wrapping ErrInvalidRecord is returned, or
+	// ErrTooLarge if the line is too long.  In both cases, the next
+	// call continues with the next line.  Other errors such as I/O
+	// errors are not recoverable.
+	Decode() (*Record, error)
+}
+
+// NewDecoder returns a Decoder for outputs of the built-in formatter
+// named format: "plain", "logfmt", "json", or "msgpack".
+func NewDecoder(format string, r io.Reader) (Decoder, error) {
+	switch format {
+	case "plain":
+		return NewPlainDecoder(r), nil
+	case "logfmt":
+		return NewLogfmtDecoder(r), nil
+	case "json":
+		return NewJSONDecoder(r), nil
+	case "msgpack":
+		return NewMsgPackDecoder(r), nil
+	}
+	return nil, fmt.Errorf("no decoder for format: %s", format)
+}
+
+// NewPlainDecoder returns a Decoder for PlainFormat output.
+func NewPlainDecoder(r io.Reader) Decoder {
+	return &lineDecoder{r: bufio.NewReader(r), parse: parsePlain}
+}
+
+// NewLogfmtDecoder returns a Decoder for Logfmt output.
+func NewLogfmtDecoder(r io.Reader) Decoder {
+	return &lineDecoder{r: bufio.NewReader(r), parse: parseLogfmt}
+}
+
+// NewJSONDecoder returns a Decoder for JSONFormat output.
+func NewJSONDecoder(r io.Reader) Decoder {
+	return &lineDecoder{r: bufio.NewReader(r), parse: parseJSON}
+}
+
+// NewMsgPackDecoder returns a Decoder for MsgPack output.
+func NewMsgPackDecoder(r io.Reader) Decoder {
+	return &msgpackRecordDecoder{r: bufio.NewReader(r)}
+}
+
+// lineDecoder decodes records from lines.
+type lineDecoder struct {
+	r     *bufio.Reader
+	parse func(line []byte) (*Record, error)
+
+	// pending is the partial line read so far.
+	pending []byte
+	// skip is true while skipping a too long line.
+	skip bool
+}
+
+func (d *lineDecoder) Decode() (*Record, error) {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		r, err := d.parse(line)
+		if err != nil && !errors.Is(err, ErrInvalidRecord) {
+			err = fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+		}
+		return r, err
+	}
+}
+
+// readLine returns the next line without the trailing newline.
+// The returned slice is valid until the next call.
+func (d *lineDecoder) readLine() ([]byte, error) {
+	for {
+		b, err := d.r.ReadSlice('\n')
+		if !d.skip {
+			if len(d.pending)+len(b) > maxLogSize {
+				d.skip = true
+				d.pending = d.pending[:0]
+			} else {
+				d.pending = append(d.pending, b...)
+			}
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && (d.skip || len(d.pending) > 0):
+			return nil, io.ErrUnexpectedEOF
+		case err != nil:
+			return nil, err
+		}
+
+		if d.skip {
+			d.skip = false
+			return nil, ErrTooLarge
+		}
+		line := d.pending[:len(d.pending)-1]
+		d.pending = d.pending[:0]
+		return line, nil
+	}
+}
+
+// msgpackRecordDecoder decodes records from MsgPack output.
+//
+// msgpackRecordDecoder implements msgpackReader to record bytes of
+// the current record so that a partial record can be decoded again.
+type msgpackRecordDecoder struct {
+	r       *bufio.Reader
+	pending []byte
+	pos     int
+}
+
+func (d *msgpackRecordDecoder) ReadByte() (byte, error) {
+	if d.pos < len(d.pending) {
+		c := d.pending[d.pos]
+		d.pos++
+		return c, nil
+	}
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	d.pending = append(d.pending, c)
+	d.pos++
+	return c, nil
+}
+
+func (d *msgpackRecordDecoder) Read(p []byte) (int, error) {
+	if d.pos < len(d.pending) {
+		n := copy(p, d.pending[d.pos:])
+		d.pos += n
+		return n, nil
+	}
+	n, err := d.r.Read(p)
+	d.pending = append(d.pending, p[:n]...)
+	d.pos += n
+	return n, err
+}
+
+func (d *msgpackRecordDecoder) Decode() (*Record, error) {
+	d.pos = 0
+	v, err := (&msgpackDecoder{d}).decode()
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return nil, err
+	}
+	d.pending = d.pending[:0]
+	if err != nil {
+		return nil, err
+	}
+
+	// [topic, time, record]
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 3 {
+		return nil, ErrInvalidRecord
+	}
+	m, ok := a[2].(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidRecord
+	}
+	if _, ok := m[FnLoggedAt]; !ok {
+		switch t := a[1].(type) {
+		case int64:
+			m[FnLoggedAt] = time.Unix(t, 0).UTC()
+		case time.Time:
+			m[FnLoggedAt] = t
+		}
+	}
+	m[FnTopic] = a[0]
+	return newRecord(m)
+}
+
+// newRecord creates a record from a map of all fields.
+func newRecord(m map[string]interface{}) (*Record, error) {
+	r := &Record{Fields: make(map[string]interface{}, len(m))}
+	for k, v := range m {
+		var err error
+		switch k {
+		case FnTopic:
+			r.Topic, err = recordString(k, v)
+		case FnLoggedAt:
+			r.LoggedAt, err = recordTime(v)
+		case FnSeverity:
+			r.Severity, err = recordSeverity(v)
+		case FnUtsname:
+			r.Utsname, err = recordString(k, v)
+		case FnMessage:
+			r.Message, err = recordString(k, v)
+		default:
+			r.Fields[k] = v
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func recordString(k string, v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidData, k)
+	}
+	return s, nil
+}
+
+// recordTime converts RFC3339 strings and integers in microseconds
+// to time.Time.
+func recordTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case int64:
+		return time.UnixMicro(t).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidData, FnLoggedAt)
+}
+
+// recordSeverity converts level names and integers to a severity.
+func recordSeverity(v interface{}) (int, error) {
+	switch t := v.(type) {
+	case int64:
+		return int(t), nil
+	case string:
+		if n, err := strconv.Atoi(t); err == nil {
+			return n, nil
+		}
+		return levelByName(t)
+	}
+	return 0, fmt.Errorf("%w: %s", ErrInvalidData, FnSeverity)
+}
+
+func parseJSON(line []byte) (*Record, error) {
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		m[k] = convertJSONNumber(v)
+	}
+	return newRecord(m)
+}
+
+// convertJSONNumber converts json.Number in v into int64 or float64.
+func convertJSONNumber(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(string(t), 10, 64); err == nil {
+			return n
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return string(t)
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = convertJSONNumber(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = convertJSONNumber(e)
+		}
+	}
+	return v
+}
+
+func parseLogfmt(line []byte) (*Record, error) {
+	m := make(map[string]interface{})
+	sc := &logfmtScanner{s: string(line)}
+	if err := sc.pairs(m); err != nil {
+		return nil, err
+	}
+	return newRecord(m)
+}
+
+// parsePlain parses a line in the form of:
+// DATETIME UTSNAME TOPIC SEVERITY: MESSAGE [OPTIONAL FIELDS...]
+func parsePlain(line []byte) (*Record, error) {
+	s := string(line)
+	date, s, ok1 := strings.Cut(s, " ")
+	host, s, ok2 := strings.Cut(s, " ")
+	topic, s, ok3 := strings.Cut(s, " ")
+	sev, s, ok4 := strings.Cut(s, ": ")
+	if !ok1 || !ok2 || !ok3 || !ok4 || !strings.HasPrefix(s, `"`) {
+		return nil, ErrInvalidRecord
+	}
+
+	r := &Record{
+		Topic:   topic,
+		Utsname: host,
+		Fields:  make(map[string]interface{}),
+	}
+	var err error
+	r.LoggedAt, err = time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		return nil, err
+	}
+	r.Severity, err = recordSeverity(sev)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &logfmtScanner{s: s}
+	r.Message, err = sc.quoted()
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.pairs(r.Fields); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// logfmtScanner parses key=value pairs formatted by appendLogfmt
+// and appendPlain.
+type logfmtScanner struct {
+	s   string
+	pos int
+}
+
+func (sc *logfmtScanner) skipSpaces() {
+	for sc.pos < len(sc.s) && sc.s[sc.pos] == ' ' {
+		sc.pos++
+	}
+}
+
+// pairs parses space-separated key=value pairs into m.
+func (sc *logfmtScanner) pairs(m map[string]interface{}) error {
+	for {
+		sc.skipSpaces()
+		if sc.pos == len(sc.s) {
+			return nil
+		}
+		key, err := sc.key()
+		if err != nil {
+			return err
+		}
+
+		// topic and utsname are not quoted even if they look like
+		// other types.
+		if (key == FnTopic || key == FnUtsname) && !strings.HasPrefix(sc.s[sc.pos:], `"`) {
+			m[key] = sc.token()
+			continue
+		}
+		v, err := sc.value()
+		if err != nil {
+			return err
+		}
+		m[key] = v
+	}
+}
+
+// key parses a bare or quoted key followed by '='.
+func (sc *logfmtScanner) key() (string, error) {
+	var key string
+	if strings.HasPrefix(sc.s[sc.pos:], `"`) {
+		k, err := sc.quoted()
+		if err != nil {
+			return "", err
+		}
+		key = k
+	} else {
+		i := strings.IndexAny(sc.s[sc.pos:], "= ")
+		if i == -1 {
+			return "", ErrInvalidRecord
+		}
+		key = sc.s[sc.pos : sc.pos+i]
+		sc.pos += i
+	}
+	if sc.pos == len(sc.s) || sc.s[sc.pos] != '=' || len(key) == 0 {
+		return "", ErrInvalidRecord
+	}
+	sc.pos++
+	return key, nil
+}
+
+func (sc *logfmtScanner) value() (interface{}, error) {
+	if sc.pos == len(sc.s) {
+		return "", nil
+	}
+	switch sc.s[sc.pos] {
+	case '"':
+		return sc.quoted()
+	case '{':
+		return sc.mapValue()
+	case '[':
+		return sc.arrayValue()
+	}
+	return bareValue(sc.token()), nil
+}
+
+// token returns the bare token up to a space, '}', or ']'.
+func (sc *logfmtScanner) token() string {
+	start := sc.pos
+	for sc.pos < len(sc.s) {
+		switch sc.s[sc.pos] {
+		case ' ', '}', ']':
+			return sc.s[start:sc.pos]
+		}
+		sc.pos++
+	}
+	return sc.s[start:]
+}
+
+// quoted parses a string quoted by strconv.Quote.
+func (sc *logfmtScanner) quoted() (string, error) {
+	q, err := strconv.QuotedPrefix(sc.s[sc.pos:])
+	if err != nil {
+		return "", ErrInvalidRecord
+	}
+	sc.pos += len(q)
+	return strconv.Unquote(q)
+}
+
+func (sc *logfmtScanner) mapValue() (interface{}, error) {
+	m := make(map[string]interface{})
+	sc.pos++
+	for {
+		sc.skipSpaces()
+		if sc.pos == len(sc.s) {
+			return nil, ErrInvalidRecord
+		}
+		if sc.s[sc.pos] == '}' {
+			sc.pos++
+			return m, nil
+		}
+		key, err := sc.key()
+		if err != nil {
+			return nil, err
+		}
+		v, err := sc.value()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+func (sc *logfmtScanner) arrayValue() (interface{}, error) {
+	a := []interface{}{}
+	sc.pos++
+	for {
+		sc.skipSpaces()
+		if sc.pos == len(sc.s) {
+			return nil, ErrInvalidRecord
+		}
+		if sc.s[sc.pos] == ']' {
+			sc.pos++
+			return a, nil
+		}
+		v, err := sc.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+}
+
+// bareValue converts an unquoted token into a value.
+func bareValue(s string) interface{} {
+	switch s {
+	case "null":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	return s
+}
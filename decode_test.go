@@ -0,0 +1,189 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+
+	loggedAt := time.Date(2001, 12, 3, 13, 45, 1, 123456000, time.UTC)
+	fields := map[string]interface{}{
+		"int":    -3,
+		"uint":   uint64(1 << 63),
+		"float":  3.5,
+		"bool":   true,
+		"null":   nil,
+		"str":    "a \"quoted\"\nstring {x=1}",
+		"time":   loggedAt,
+		"slice":  []interface{}{1, "a b", false},
+		"map":    map[string]interface{}{"k": "v", "a b": 1},
+		"number": "123",
+	}
+
+	testCases := []struct {
+		formatter Formatter
+		expected  map[string]interface{}
+	}{
+		{PlainFormat{Utsname: "host1"}, map[string]interface{}{
+			"int":    int64(-3),
+			"uint":   uint64(1 << 63),
+			"float":  3.5,
+			"bool":   true,
+			"null":   nil,
+			"str":    "a \"quoted\"\nstring {x=1}",
+			"time":   loggedAt,
+			"slice":  "[1 a b false]",
+			"map":    "map[a b:1 k:v]",
+			"number": "123",
+		}},
+		{Logfmt{Utsname: "host1"}, map[string]interface{}{
+			"int":    int64(-3),
+			"uint":   uint64(1 << 63),
+			"float":  3.5,
+			"bool":   true,
+			"null":   nil,
+			"str":    "a \"quoted\"\nstring {x=1}",
+			"time":   loggedAt,
+			"slice":  []interface{}{int64(1), "a b", false},
+			"map":    map[string]interface{}{"k": "v", "a b": int64(1)},
+			"number": "123",
+		}},
+		{JSONFormat{Utsname: "host1"}, map[string]interface{}{
+			"int":    int64(-3),
+			"uint":   uint64(1 << 63),
+			"float":  3.5,
+			"bool":   true,
+			"null":   nil,
+			"str":    "a \"quoted\"\nstring {x=1}",
+			"time":   "2001-12-03T13:45:01.123456Z",
+			"slice":  []interface{}{int64(1), "a b", false},
+			"map":    map[string]interface{}{"k": "v", "a b": int64(1)},
+			"number": "123",
+		}},
+		{MsgPack{Utsname: "host1", TimestampExt: true}, map[string]interface{}{
+			"int":    int64(-3),
+			"uint":   uint64(1 << 63),
+			"float":  3.5,
+			"bool":   true,
+			"null":   nil,
+			"str":    "a \"quoted\"\nstring {x=1}",
+			"time":   loggedAt,
+			"slice":  []interface{}{int64(1), "a b", false},
+			"map":    map[string]interface{}{"k": "v", "a b": int64(1)},
+			"number": "123",
+		}},
+	}
+
+	for _, tc := range testCases {
+		l := NewLogger()
+		l.SetTopic("1e5")
+
+		var buf []byte
+		var err error
+		buf, err = tc.formatter.Format(buf, l, loggedAt, LvWarn, "hello \"world\"", fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err = tc.formatter.Format(buf, l, loggedAt, 99, "second", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := NewDecoder(tc.formatter.String(), bytes.NewReader(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := d.Decode()
+		if err != nil {
+			t.Fatal(tc.formatter, err)
+		}
+		expected := &Record{
+			Topic:    "1e5",
+			LoggedAt: loggedAt,
+			Severity: LvWarn,
+			Utsname:  "host1",
+			Message:  "hello \"world\"",
+			Fields:   tc.expected,
+		}
+		if !r.LoggedAt.Equal(expected.LoggedAt) {
+			t.Errorf("%s: unexpected logged_at: %v", tc.formatter, r.LoggedAt)
+		}
+		r.LoggedAt = loggedAt
+		if !reflect.DeepEqual(r, expected) {
+			t.Errorf("%s: unexpected record:\n%#v\n%#v", tc.formatter, r, expected)
+		}
+
+		r, err = d.Decode()
+		if err != nil {
+			t.Fatal(tc.formatter, err)
+		}
+		if r.Severity != 99 || r.Message != "second" || len(r.Fields) != 0 {
+			t.Errorf("%s: unexpected record: %#v", tc.formatter, r)
+		}
+
+		if _, err := d.Decode(); err != io.EOF {
+			t.Errorf("%s: expected io.EOF, got %v", tc.formatter, err)
+		}
+	}
+
+	if _, err := NewDecoder("xml", nil); err == nil {
+		t.Error("xml should not be supported")
+	}
+}
+
+func TestDecoderPartial(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("partial")
+	for _, f := range []Formatter{PlainFormat{}, Logfmt{}, JSONFormat{}, MsgPack{}} {
+		b, err := f.Format(nil, l, time.Now(), LvInfo, "hello", map[string]interface{}{"abc": 123})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := new(bytes.Buffer)
+		d, _ := NewDecoder(f.String(), buf)
+		buf.Write(b[:len(b)-5])
+		if _, err := d.Decode(); err != io.ErrUnexpectedEOF {
+			t.Errorf("%s: expected io.ErrUnexpectedEOF, got %v", f, err)
+		}
+		buf.Write(b[len(b)-5:])
+		r, err := d.Decode()
+		if err != nil {
+			t.Fatal(f, err)
+		}
+		if r.Topic != "partial" || r.Message != "hello" || r.Fields["abc"] != int64(123) {
+			t.Errorf("%s: unexpected record: %#v", f, r)
+		}
+	}
+}
+
+func TestDecoderMalformed(t *testing.T) {
+	t.Parallel()
+
+	input := `topic=a logged_at=2001-12-03T13:45:01.123456Z severity=info utsname=h message="m" x="unterminated
+topic=b logged_at=2001-12-03T13:45:01.123456Z severity=info utsname=h message="m" x={a=1 b=[1 2]}
+
+`
+	d := NewLogfmtDecoder(bytes.NewBufferString(input))
+	if _, err := d.Decode(); err == nil {
+		t.Error("malformed line should be an error")
+	}
+	r, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"a": int64(1), "b": []interface{}{int64(1), int64(2)}}
+	if r.Topic != "b" || !reflect.DeepEqual(r.Fields["x"], expected) {
+		t.Errorf("unexpected record: %#v", r)
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
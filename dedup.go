@@ -0,0 +1,147 @@
+package log
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dedupState keeps the last log to suppress consecutive duplicates.
+type dedupState struct {
+	mu sync.Mutex
+
+	// the last log output.
+	logger   *Logger
+	severity int
+	msg      string
+	fields   map[string]interface{}
+	expireAt time.Time
+
+	// suppressed duplicates of the last log.
+	repeated int
+	firstAt  time.Time
+	lastAt   time.Time
+
+	timer *time.Timer
+	// gen is incremented when the last log is replaced.
+	gen uint64
+}
+
+// SetDedupWindow enables suppression of consecutive duplicate logs.
+//
+// If a log has the same severity, message, and fields as the previous
+// log from the same logger within window since the previous log was
+// output, the log is suppressed.  At the end of the window, or when
+// a different log is output, a summary log is output with the same
+// severity, message, and fields plus FnRepeated, FnFirstAt, and FnLastAt
+// fields for the number and the time range of the suppressed logs.
+//
+// Zero disables the suppression, which is the default.  This setting
+// is shared with loggers derived by With.
+func (l *Logger) SetDedupWindow(window time.Duration) {
+	atomic.StoreInt64(&l.dedupWindow, int64(window))
+	if window == 0 {
+		l.flushDedup()
+	}
+}
+
+// DedupWindow returns the window to suppress duplicate logs.
+func (l *Logger) DedupWindow() time.Duration {
+	return time.Duration(atomic.LoadInt64(&l.dedupWindow))
+}
+
+func equalFields(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// writeDedup writes a log unless it is a duplicate of the last log.
+func (l *Logger) writeDedup(window time.Duration, t time.Time, severity int, msg string,
+	fields map[string]interface{}) error {
+	d := &l.dedup
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.logger == l && d.severity == severity && d.msg == msg &&
+		now.Before(d.expireAt) && equalFields(d.fields, fields) {
+		if d.repeated == 0 {
+			d.firstAt = now
+		}
+		d.repeated++
+		d.lastAt = now
+		if d.timer == nil {
+			gen := d.gen
+			d.timer = time.AfterFunc(d.expireAt.Sub(now), func() {
+				d.mu.Lock()
+				defer d.mu.Unlock()
+				if d.gen != gen {
+					return
+				}
+				d.writeSummary()
+				d.reset()
+			})
+		}
+		return nil
+	}
+
+	err := d.writeSummary()
+	d.reset()
+	d.logger = l
+	d.severity = severity
+	d.msg = msg
+	if len(fields) > 0 {
+		d.fields = make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			d.fields[k] = v
+		}
+	}
+	d.expireAt = now.Add(window)
+
+	if werr := l.writeAt(t, severity, msg, fields); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// flushDedup outputs the summary of suppressed logs if any.
+func (l *Logger) flushDedup() error {
+	d := &l.dedup
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := d.writeSummary()
+	d.reset()
+	return err
+}
+
+// writeSummary outputs the summary of suppressed logs if any.
+// d.mu must be held.
+func (d *dedupState) writeSummary() error {
+	if d.repeated == 0 {
+		return nil
+	}
+	fields := make(map[string]interface{}, len(d.fields)+3)
+	for k, v := range d.fields {
+		fields[k] = v
+	}
+	fields[FnRepeated] = d.repeated
+	fields[FnFirstAt] = d.firstAt
+	fields[FnLastAt] = d.lastAt
+	return d.logger.write(d.severity, d.msg, fields)
+}
+
+// reset forgets the last log.  d.mu must be held.
+func (d *dedupState) reset() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.gen++
+	d.logger = nil
+	d.fields = nil
+	d.repeated = 0
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	l.SetDedupWindow(time.Hour)
+
+	for i := 0; i < 5; i++ {
+		if err := l.Error("flapping", map[string]interface{}{"abc": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Error("flapping", map[string]interface{}{"abc": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Error("flapping", map[string]interface{}{"abc": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []map[string]interface{}
+	for len(ch) > 0 {
+		var record map[string]interface{}
+		if err := json.Unmarshal(<-ch, &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 4 {
+		t.Fatalf("4 logs are expected but %d: %v", len(records), records)
+	}
+
+	expected := []struct {
+		abc      float64
+		repeated interface{}
+	}{
+		{1, nil},
+		{1, 4.0},
+		{2, nil},
+		{2, 1.0},
+	}
+	for i, e := range expected {
+		r := records[i]
+		if r["abc"] != e.abc || r[FnRepeated] != e.repeated || r[FnMessage] != "flapping" {
+			t.Errorf("unexpected log #%d: %v", i, r)
+		}
+	}
+	for _, k := range []string{FnFirstAt, FnLastAt} {
+		if _, ok := records[1][k].(string); !ok {
+			t.Errorf("%s should be set: %v", k, records[1])
+		}
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	l.SetDedupWindow(100 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if err := l.Warn("flapping", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-ch
+
+	// the summary is output at the end of the window.
+	var record map[string]interface{}
+	select {
+	case b := <-ch:
+		if err := json.Unmarshal(b, &record); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("summary log is not output")
+	}
+	if record[FnRepeated] != 2.0 || record[FnSeverity] != "warning" {
+		t.Errorf("unexpected summary: %v", record)
+	}
+
+	// the same log is output again after the window.
+	if err := l.Warn("flapping", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 1 {
+		t.Error("log should be output after the window")
+	}
+
+	// different loggers are not deduplicated.
+	if err := l.With(map[string]interface{}{"abc": 1}).Warn("flapping", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 2 {
+		t.Error("log of another logger should be output")
+	}
+}
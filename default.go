@@ -1,14 +1,22 @@
 package log
 
 import (
+	"context"
 	_log "log"
 	"os"
+	"time"
 )
 
 const (
-	// EnvLogLevel ks the environment variable name to configure
+	// EnvLogLevel is the environment variable name to configure
 	// the default logger's log level at program startup.
+	//
+	// The default logger is also configured by other environment
+	// variables.  See ConfigureFromEnv.
 	EnvLogLevel = "CYBOZU_LOG_LEVEL"
+
+	// exitFlushTimeout is the maximum duration to flush logs in ErrorExit.
+	exitFlushTimeout = 5 * time.Second
 )
 
 var (
@@ -21,9 +29,10 @@ func init() {
 	// no date/time needed
 	_log.SetFlags(0)
 
-	level := os.Getenv(EnvLogLevel)
-	if len(level) > 0 {
-		defaultLogger.SetThresholdByName(level)
+	if err := ConfigureFromEnv(defaultLogger); err != nil {
+		defaultLogger.Error("invalid logging configuration in environment variables", map[string]interface{}{
+			FnError: err.Error(),
+		})
 	}
 }
 
@@ -67,8 +76,47 @@ func Debug(msg string, fields map[string]interface{}) error {
 	return defaultLogger.Log(LvDebug, msg, fields)
 }
 
+// CriticalContext outputs a critical log using the logger in ctx.
+// If ctx has no logger, the default logger is used.
+// Fields in ctx are added to the log.  fields can be nil.
+func CriticalContext(ctx context.Context, msg string, fields map[string]interface{}) error {
+	return FromContext(ctx).LogContext(ctx, LvCritical, msg, fields)
+}
+
+// ErrorContext outputs an error log using the logger in ctx.
+// If ctx has no logger, the default logger is used.
+// Fields in ctx are added to the log.  fields can be nil.
+func ErrorContext(ctx context.Context, msg string, fields map[string]interface{}) error {
+	return FromContext(ctx).LogContext(ctx, LvError, msg, fields)
+}
+
+// WarnContext outputs a warning log using the logger in ctx.
+// If ctx has no logger, the default logger is used.
+// Fields in ctx are added to the log.  fields can be nil.
+func WarnContext(ctx context.Context, msg string, fields map[string]interface{}) error {
+	return FromContext(ctx).LogContext(ctx, LvWarn, msg, fields)
+}
+
+// InfoContext outputs an informational log using the logger in ctx.
+// If ctx has no logger, the default logger is used.
+// Fields in ctx are added to the log.  fields can be nil.
+func InfoContext(ctx context.Context, msg string, fields map[string]interface{}) error {
+	return FromContext(ctx).LogContext(ctx, LvInfo, msg, fields)
+}
+
+// DebugContext outputs a debug log using the logger in ctx.
+// If ctx has no logger, the default logger is used.
+// Fields in ctx are added to the log.  fields can be nil.
+func DebugContext(ctx context.Context, msg string, fields map[string]interface{}) error {
+	return FromContext(ctx).LogContext(ctx, LvDebug, msg, fields)
+}
+
 // ErrorExit outputs an error log using the default logger, then exit.
+// Logs buffered in the output of the default logger are flushed before exit.
 func ErrorExit(err error) {
 	Error(err.Error(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), exitFlushTimeout)
+	defaultLogger.Flush(ctx)
+	cancel()
 	os.Exit(1)
 }
@@ -22,6 +22,11 @@ built-in formatters are available.
 	Plain (default): syslog like text formatter.
 	logfmt:          https://gist.github.com/kr/0e8d5ee4b954ce604bb2
 	JSON Lines:      https://jsonlines.org/
+	MessagePack:     https://msgpack.org/
+	syslog:          RFC 5424 and RFC 3164
+	journal:         native protocol of systemd-journald (Linux only)
+	console:         human-friendly colorized text for development
+	GELF:            Graylog Extended Log Format version 1.1
 
 The standard field names are defined as constants in this package.
 For example, "secret" is defined as FnSecret.
@@ -0,0 +1,145 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Environment variables to configure loggers by ConfigureFromEnv.
+// EnvLogLevel is also used.
+const (
+	// EnvLogFormat is the formatter name registered by
+	// RegisterFormatter such as "json".
+	EnvLogFormat = "CYBOZU_LOG_FORMAT"
+
+	// EnvLogOutput is the output: "stderr", "stdout", or a file path.
+	EnvLogOutput = "CYBOZU_LOG_OUTPUT"
+
+	// EnvLogReopenSignal is the signal name such as "SIGUSR1" to reopen
+	// the output file.  Only for non-Windows systems.
+	EnvLogReopenSignal = "CYBOZU_LOG_REOPEN_SIGNAL"
+
+	// EnvLogTopic overrides the topic.
+	EnvLogTopic = "CYBOZU_LOG_TOPIC"
+
+	// EnvLogUtsname overrides the hostname in logs.
+	EnvLogUtsname = "CYBOZU_LOG_UTSNAME"
+
+	// EnvLogDefaults is a JSON object of default field values.
+	EnvLogDefaults = "CYBOZU_LOG_DEFAULTS"
+)
+
+// ConfigureFromEnv configures l by environment variables:
+//
+//	CYBOZU_LOG_LEVEL:         threshold name such as "debug"
+//	CYBOZU_LOG_FORMAT:        formatter name such as "json"
+//	CYBOZU_LOG_OUTPUT:        "stderr", "stdout", or a file path
+//	CYBOZU_LOG_REOPEN_SIGNAL: signal name to reopen the output file
+//	CYBOZU_LOG_TOPIC:         topic matching [.a-z0-9-]+
+//	CYBOZU_LOG_UTSNAME:       hostname in logs
+//	CYBOZU_LOG_DEFAULTS:      JSON object of default field values
+//
+// Empty or unset variables are ignored.  If CYBOZU_LOG_UTSNAME is set
+// without CYBOZU_LOG_FORMAT, "plain" formatter is used.
+//
+// If any variable is invalid, l is not modified and an error describing
+// all invalid variables is returned.
+func ConfigureFromEnv(l *Logger) error {
+	var errs []error
+	envError := func(name string, err error) {
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	level := l.Threshold()
+	if v := os.Getenv(EnvLogLevel); len(v) > 0 {
+		lv, err := levelByName(v)
+		if err != nil {
+			envError(EnvLogLevel, err)
+		}
+		level = lv
+	}
+
+	var formatter Formatter
+	format := os.Getenv(EnvLogFormat)
+	utsname := os.Getenv(EnvLogUtsname)
+	if len(format) > 0 || len(utsname) > 0 {
+		if len(format) == 0 {
+			format = "plain"
+		}
+		f, err := newFormatter(format, utsname)
+		if err != nil {
+			envError(EnvLogFormat, err)
+		}
+		formatter = f
+	}
+
+	var defaults map[string]interface{}
+	if v := os.Getenv(EnvLogDefaults); len(v) > 0 {
+		if err := json.Unmarshal([]byte(v), &defaults); err != nil {
+			envError(EnvLogDefaults, err)
+		}
+		for k := range defaults {
+			if !IsValidKey(k) {
+				envError(EnvLogDefaults, fmt.Errorf("%w: %s", ErrInvalidKey, k))
+			}
+		}
+	}
+
+	topic := os.Getenv(EnvLogTopic)
+	if len(topic) > 0 && !isValidTopic(topic) {
+		envError(EnvLogTopic, fmt.Errorf("invalid topic: %q", topic))
+	}
+
+	var output io.Writer
+	var sig os.Signal
+	path := os.Getenv(EnvLogOutput)
+	if v := os.Getenv(EnvLogReopenSignal); len(v) > 0 {
+		switch path {
+		case "", "stderr", "stdout":
+			envError(EnvLogReopenSignal, errors.New("output is not a file"))
+		default:
+			s, err := parseReopenSignal(v)
+			if err != nil {
+				envError(EnvLogReopenSignal, err)
+			}
+			sig = s
+		}
+	}
+	switch path {
+	case "stderr":
+		output = os.Stderr
+	case "stdout":
+		output = os.Stdout
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// open the file at last as it cannot be undone.
+	if output == nil && len(path) > 0 {
+		w, err := openOutputFile(path, sig)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvLogOutput, err)
+		}
+		output = w
+	}
+
+	l.SetThreshold(level)
+	if formatter != nil {
+		l.SetFormatter(outputFormatter(format, formatter, l, output))
+	}
+	if defaults != nil {
+		l.SetDefaults(defaults)
+	}
+	if len(topic) > 0 {
+		l.SetTopic(topic)
+	}
+	if output != nil {
+		l.SetOutput(output)
+	}
+	return nil
+}
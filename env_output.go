@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+)
+
+var reopenSignals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// parseReopenSignal returns the signal to reopen log files.
+// The "SIG" prefix of name can be omitted.
+func parseReopenSignal(name string) (os.Signal, error) {
+	n := strings.ToUpper(name)
+	if !strings.HasPrefix(n, "SIG") {
+		n = "SIG" + n
+	}
+	s, ok := reopenSignals[n]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signal: %s", name)
+	}
+	return s, nil
+}
+
+// openOutputFile opens the log file at path.
+// If sig is not nil, the file is reopened when the signal is received.
+func openOutputFile(path string, sig os.Signal) (io.Writer, error) {
+	if sig == nil {
+		return openLogFile(path)
+	}
+	return NewFileReopener(path, sig)
+}
@@ -0,0 +1,18 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// parseReopenSignal returns an error as reopening log files by signals
+// is not supported on Windows.
+func parseReopenSignal(name string) (os.Signal, error) {
+	return nil, errors.New("reopening by signals is not supported on Windows")
+}
+
+// openOutputFile opens the log file at path.
+func openOutputFile(path string, sig os.Signal) (io.Writer, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
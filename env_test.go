@@ -0,0 +1,75 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigureFromEnv(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "test.log")
+	t.Setenv(EnvLogLevel, "debug")
+	t.Setenv(EnvLogFormat, "json")
+	t.Setenv(EnvLogOutput, logfile)
+	t.Setenv(EnvLogTopic, "envtest")
+	t.Setenv(EnvLogUtsname, "envhost")
+	t.Setenv(EnvLogDefaults, `{"abc": 1, "def": "x"}`)
+
+	l := NewLogger()
+	if err := ConfigureFromEnv(l); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Debug("hello", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		FnTopic:    "envtest",
+		FnUtsname:  "envhost",
+		FnSeverity: "debug",
+		"abc":      1.0,
+		"def":      "x",
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("%s: got %v, want %v", k, record[k], v)
+		}
+	}
+}
+
+func TestConfigureFromEnvInvalid(t *testing.T) {
+	t.Setenv(EnvLogLevel, "verbose")
+	t.Setenv(EnvLogFormat, "xml")
+	t.Setenv(EnvLogOutput, "stdout")
+	t.Setenv(EnvLogReopenSignal, "SIGUSR1")
+	t.Setenv(EnvLogDefaults, `{"Bad": 1}`)
+	t.Setenv(EnvLogTopic, "My App")
+
+	l := NewLogger()
+	topic := l.Topic()
+	err := ConfigureFromEnv(l)
+	if err == nil {
+		t.Fatal("invalid configuration should be reported")
+	}
+	for _, name := range []string{EnvLogLevel, EnvLogFormat, EnvLogReopenSignal, EnvLogDefaults, EnvLogTopic} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error should mention %s: %v", name, err)
+		}
+	}
+	if l.Threshold() != LvInfo || l.Formatter().String() != "plain" || l.Defaults() != nil || l.Topic() != topic {
+		t.Error("logger should not be modified")
+	}
+}
@@ -11,4 +11,7 @@ var (
 
 	// ErrInvalidData is returned when fields contain invalid data.
 	ErrInvalidData = errors.New("invalid data type")
+
+	// ErrInvalidRecord is returned by Decoder for malformed records.
+	ErrInvalidRecord = errors.New("invalid log record")
 )
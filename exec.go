@@ -0,0 +1,219 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// LogCmd is a wrapper of exec.Cmd that outputs an "exec" type log
+// when the command finishes.  Logs have these fields:
+//
+//	type=exec, args, exit_status, response_time, user_time,
+//	system_time, and error if the command fails.
+//
+// If StdoutSeverity or StderrSeverity is not zero and the corresponding
+// Stdout or Stderr of Cmd is nil, each line of the output of the command
+// is logged with "stream" field set to "stdout" or "stderr".
+//
+// Use Run, Start and Wait, Output, or CombinedOutput of LogCmd rather than
+// those of the embedded exec.Cmd.
+type LogCmd struct {
+	*exec.Cmd
+
+	// Logger is used to output logs.
+	// If nil, the logger in the context given to CommandContext is used.
+	Logger *Logger
+
+	// Severity returns the severity of the exec log for the result.
+	// If nil, LvError is used for errors and LvInfo for others.
+	Severity func(err error) int
+
+	// StdoutSeverity and StderrSeverity are the severities to log
+	// the output of the command.  Zero disables logging.
+	StdoutSeverity int
+	StderrSeverity int
+
+	ctx     context.Context
+	startAt time.Time
+	streams []*streamWriter
+}
+
+// Command returns a LogCmd to execute the named program.
+// See exec.Command for details.
+func Command(name string, args ...string) *LogCmd {
+	return &LogCmd{
+		Cmd: exec.Command(name, args...),
+		ctx: context.Background(),
+	}
+}
+
+// CommandContext returns a LogCmd to execute the named program.
+// See exec.CommandContext for details.
+//
+// Logs are output by Logger.LogContext with ctx, so that fields stored
+// in ctx such as the request ID are added.
+func CommandContext(ctx context.Context, name string, args ...string) *LogCmd {
+	return &LogCmd{
+		Cmd: exec.CommandContext(ctx, name, args...),
+		ctx: ctx,
+	}
+}
+
+func (c *LogCmd) logger() *Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return FromContext(c.ctx)
+}
+
+// Start starts the command.  See exec.Cmd.Start.
+func (c *LogCmd) Start() error {
+	if c.Stdout == nil && c.StdoutSeverity != 0 {
+		w := &streamWriter{cmd: c, severity: c.StdoutSeverity, stream: "stdout"}
+		c.Stdout = w
+		c.streams = append(c.streams, w)
+	}
+	if c.Stderr == nil && c.StderrSeverity != 0 {
+		w := &streamWriter{cmd: c, severity: c.StderrSeverity, stream: "stderr"}
+		c.Stderr = w
+		c.streams = append(c.streams, w)
+	}
+
+	c.startAt = time.Now()
+	err := c.Cmd.Start()
+	if err != nil {
+		c.log(err)
+	}
+	return err
+}
+
+// Wait waits for the command to exit and outputs the exec log.
+// See exec.Cmd.Wait.
+func (c *LogCmd) Wait() error {
+	err := c.Cmd.Wait()
+	for _, w := range c.streams {
+		w.flush()
+	}
+	c.log(err)
+	return err
+}
+
+// Run starts the command and waits for it to complete.
+// See exec.Cmd.Run.
+func (c *LogCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its standard output.
+// See exec.Cmd.Output.
+func (c *LogCmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	err := c.Run()
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns its combined standard
+// output and standard error.  See exec.Cmd.CombinedOutput.
+func (c *LogCmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("exec: Stderr already set")
+	}
+	var b syncBuffer
+	c.Stdout = &b
+	c.Stderr = &b
+	err := c.Run()
+	return b.buf.Bytes(), err
+}
+
+func (c *LogCmd) log(err error) {
+	fields := map[string]interface{}{
+		FnType:         "exec",
+		FnResponseTime: time.Since(c.startAt).Seconds(),
+		"args":         c.Args,
+	}
+	if ps := c.ProcessState; ps != nil {
+		fields["exit_status"] = ps.ExitCode()
+		fields["user_time"] = ps.UserTime().Seconds()
+		fields["system_time"] = ps.SystemTime().Seconds()
+	}
+	if err != nil {
+		fields[FnError] = err.Error()
+	}
+
+	severity := LvInfo
+	switch {
+	case c.Severity != nil:
+		severity = c.Severity(err)
+	case err != nil:
+		severity = LvError
+	}
+	c.logger().LogContext(c.ctx, severity, "exec", fields)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// streamWriter logs each line of the output of a command.
+type streamWriter struct {
+	cmd      *LogCmd
+	severity int
+	stream   string
+	buf      []byte
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	start := 0
+	for {
+		eol := bytes.IndexByte(w.buf[start:], '\n')
+		if eol == -1 {
+			break
+		}
+		w.output(w.buf[start : start+eol])
+		start += eol + 1
+	}
+	w.buf = append(w.buf[:0], w.buf[start:]...)
+	// avoid unlimited growth for output without newlines.
+	if len(w.buf) >= maxLogSize/2 {
+		w.output(w.buf)
+		w.buf = w.buf[:0]
+	}
+	return len(p), nil
+}
+
+// flush outputs the last line not terminated by a newline.
+func (w *streamWriter) flush() {
+	if len(w.buf) > 0 {
+		w.output(w.buf)
+		w.buf = w.buf[:0]
+	}
+}
+
+func (w *streamWriter) output(line []byte) {
+	w.cmd.logger().LogContext(w.cmd.ctx, w.severity, string(line), map[string]interface{}{
+		"stream": w.stream,
+	})
+}
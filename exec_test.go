@@ -0,0 +1,122 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func decodeJSONLines(t *testing.T, b []byte) []map[string]interface{} {
+	t.Helper()
+
+	var records []map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	for d.More() {
+		var r map[string]interface{}
+		if err := d.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestLogCmd(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	ctx := WithRequestID(context.Background(), "1234")
+	cmd := CommandContext(ctx, "sh", "-c", "echo out1; echo err1 >&2; printf out2; exit 3")
+	cmd.Logger = l
+	cmd.StdoutSeverity = LvInfo
+	cmd.StderrSeverity = LvWarn
+	if err := cmd.Run(); err == nil {
+		t.Fatal("the command should fail")
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 4 {
+		t.Fatalf("4 logs are expected but %d: %s", len(records), buf.String())
+	}
+
+	lines := map[string]map[string]interface{}{}
+	for _, r := range records[:3] {
+		lines[r[FnMessage].(string)] = r
+	}
+	for msg, stream := range map[string]string{"out1": "stdout", "err1": "stderr", "out2": "stdout"} {
+		r, ok := lines[msg]
+		if !ok {
+			t.Errorf("%s should be logged", msg)
+			continue
+		}
+		if r["stream"] != stream || r[FnRequestID] != "1234" {
+			t.Errorf("unexpected log: %v", r)
+		}
+	}
+	if lines["err1"][FnSeverity] != "warning" {
+		t.Errorf("stderr should be logged as warning: %v", lines["err1"])
+	}
+
+	r := records[3]
+	if r[FnType] != "exec" || r[FnSeverity] != "error" || r["exit_status"] != 3.0 || r[FnRequestID] != "1234" {
+		t.Errorf("unexpected exec log: %v", r)
+	}
+	args, ok := r["args"].([]interface{})
+	if !ok || len(args) != 3 || args[0] != "sh" {
+		t.Errorf("unexpected args: %v", r["args"])
+	}
+	for _, k := range []string{FnResponseTime, "user_time", "system_time"} {
+		if _, ok := r[k].(float64); !ok {
+			t.Errorf("%s should be set: %v", k, r)
+		}
+	}
+	if _, ok := r[FnError]; !ok {
+		t.Errorf("error should be set: %v", r)
+	}
+}
+
+func TestLogCmdOutput(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	cmd := Command("echo", "hello")
+	cmd.Logger = l
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 {
+		t.Fatalf("1 log is expected but %d", len(records))
+	}
+	if r := records[0]; r[FnSeverity] != "info" || r["exit_status"] != 0.0 {
+		t.Errorf("unexpected exec log: %v", r)
+	}
+
+	buf.Reset()
+	cmd = Command("/nonexistent")
+	cmd.Logger = l
+	if err := cmd.Run(); err == nil {
+		t.Fatal("the command should fail to start")
+	}
+	records = decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 || records[0][FnError] == nil {
+		t.Errorf("unexpected logs: %v", records)
+	}
+}
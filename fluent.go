@@ -0,0 +1,446 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FluentMode is the event mode of Fluentd Forward protocol.
+type FluentMode int
+
+// Event modes of Fluentd Forward protocol.
+const (
+	// FluentMessage sends each event in a message.
+	FluentMessage FluentMode = iota
+
+	// FluentForward sends events of the same tag in an array.
+	FluentForward
+
+	// FluentPackedForward sends events of the same tag in a binary.
+	FluentPackedForward
+)
+
+// Default values for FluentWriter.
+const (
+	defaultFluentNetwork     = "tcp"
+	defaultFluentAddress     = "127.0.0.1:24224"
+	defaultFluentAckTimeout  = 30 * time.Second
+	defaultFluentBufferLimit = 8192
+	defaultFluentBatchSize   = 256
+	defaultFluentMinBackoff  = 500 * time.Millisecond
+	defaultFluentMaxBackoff  = time.Minute
+)
+
+// FluentWriter is an io.WriteCloser that sends logs formatted by MsgPack
+// to Fluentd using the Forward protocol.
+//
+// https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1
+//
+// Logs are queued by Write and sent by a background goroutine started
+// by the first Write.  If sending fails, FluentWriter reconnects to the
+// server with exponential backoff and resends the logs.  Logs exceeding
+// BufferLimit are dropped from the oldest.  Errors in the background
+// are reported by Flush and Close.
+//
+// Use MsgPack with EventTime enabled for sub-second timestamps.
+//
+// Fields must not be changed after the first Write.
+type FluentWriter struct {
+	// Network and Address specify the server.
+	// If empty, "tcp" and "127.0.0.1:24224" are used respectively.
+	Network string
+	Address string
+
+	// Mode is the event mode.
+	Mode FluentMode
+
+	// RequireAck enables "chunk" option for at-least-once delivery.
+	// Logs are resent unless the server acknowledges them.
+	RequireAck bool
+
+	// AckTimeout is the maximum duration to wait for an ack.
+	// If zero, 30 seconds is used.
+	AckTimeout time.Duration
+
+	// BufferLimit is the maximum number of logs waiting to be sent.
+	// If zero, 8192 is used.
+	BufferLimit int
+
+	// BatchSize is the maximum number of logs sent in a message
+	// in FluentForward or FluentPackedForward mode.
+	// If zero, 256 is used.
+	BatchSize int
+
+	// MinBackoff and MaxBackoff are the minimum and maximum intervals
+	// between retries.  If zero, 500 milliseconds and a minute are used.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	once    sync.Once
+	dropped uint64
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  []fluentEvent
+	sending  bool
+	closed   bool
+	lastErr  error
+	progress chan struct{}
+	closing  chan struct{}
+	done     chan struct{}
+
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// fluentEvent is an event parsed from a log formatted by MsgPack.
+type fluentEvent struct {
+	tag string
+	// entry is the encoded time and record.
+	entry []byte
+}
+
+func (w *FluentWriter) init() {
+	if len(w.Network) == 0 {
+		w.Network = defaultFluentNetwork
+	}
+	if len(w.Address) == 0 {
+		w.Address = defaultFluentAddress
+	}
+	if w.AckTimeout == 0 {
+		w.AckTimeout = defaultFluentAckTimeout
+	}
+	if w.BufferLimit == 0 {
+		w.BufferLimit = defaultFluentBufferLimit
+	}
+	if w.BatchSize == 0 {
+		w.BatchSize = defaultFluentBatchSize
+	}
+	if w.MinBackoff == 0 {
+		w.MinBackoff = defaultFluentMinBackoff
+	}
+	if w.MaxBackoff == 0 {
+		w.MaxBackoff = defaultFluentMaxBackoff
+	}
+	w.cond = sync.NewCond(&w.mu)
+	w.progress = make(chan struct{})
+	w.closing = make(chan struct{})
+	w.done = make(chan struct{})
+	go w.run()
+}
+
+// parseFluentEvent parses a log formatted by MsgPack.
+func parseFluentEvent(p []byte) (fluentEvent, error) {
+	if len(p) == 0 || p[0] != mpFixArray+3 {
+		return fluentEvent{}, ErrInvalidData
+	}
+	r := bytes.NewReader(p[1:])
+	d := msgpackDecoder{r}
+	tag, err := d.decode()
+	if err != nil {
+		return fluentEvent{}, err
+	}
+	s, ok := tag.(string)
+	if !ok {
+		return fluentEvent{}, ErrInvalidData
+	}
+	entry := p[len(p)-r.Len():]
+	return fluentEvent{
+		tag:   s,
+		entry: append([]byte(nil), entry...),
+	}, nil
+}
+
+// Write queues a log formatted by MsgPack.
+func (w *FluentWriter) Write(p []byte) (int, error) {
+	ev, err := parseFluentEvent(p)
+	if err != nil {
+		return 0, err
+	}
+
+	w.once.Do(w.init)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if len(w.pending) >= w.BufferLimit {
+		w.pending[0] = fluentEvent{}
+		w.pending = w.pending[1:]
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	w.pending = append(w.pending, ev)
+	w.cond.Broadcast()
+	return len(p), nil
+}
+
+// Dropped returns the number of logs dropped due to the buffer overflow
+// or failures at closing.
+func (w *FluentWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Flush waits until all queued logs are sent or ctx is done.
+func (w *FluentWriter) Flush(ctx context.Context) error {
+	w.once.Do(w.init)
+
+	w.mu.Lock()
+	for {
+		if len(w.pending) == 0 && !w.sending {
+			err := w.lastErr
+			w.lastErr = nil
+			w.mu.Unlock()
+			return err
+		}
+		ch := w.progress
+		w.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		w.mu.Lock()
+	}
+}
+
+// Close sends queued logs and closes the connection.
+// Logs that cannot be sent after one more try are dropped.
+func (w *FluentWriter) Close() error {
+	w.once.Do(w.init)
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+	w.closed = true
+	close(w.closing)
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.lastErr
+	w.lastErr = nil
+	return err
+}
+
+func (w *FluentWriter) run() {
+	defer close(w.done)
+
+	backoff := w.MinBackoff
+	for {
+		w.mu.Lock()
+		for len(w.pending) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if len(w.pending) == 0 {
+			w.mu.Unlock()
+			break
+		}
+		n := 1
+		if w.Mode != FluentMessage {
+			tag := w.pending[0].tag
+			for n < len(w.pending) && n < w.BatchSize && w.pending[n].tag == tag {
+				n++
+			}
+		}
+		batch := make([]fluentEvent, n)
+		copy(batch, w.pending)
+		w.pending = w.pending[n:]
+		w.sending = true
+		w.mu.Unlock()
+
+		err := w.send(batch)
+
+		w.mu.Lock()
+		w.sending = false
+		if err != nil {
+			w.lastErr = err
+			if w.closed {
+				atomic.AddUint64(&w.dropped, uint64(len(batch)+len(w.pending)))
+				w.pending = nil
+			} else {
+				// put back the batch for retry.
+				w.pending = append(batch, w.pending...)
+			}
+		}
+		close(w.progress)
+		w.progress = make(chan struct{})
+		w.mu.Unlock()
+
+		if err == nil {
+			backoff = w.MinBackoff
+			continue
+		}
+
+		w.disconnect()
+		select {
+		case <-time.After(backoff):
+		case <-w.closing:
+		}
+		backoff *= 2
+		if backoff > w.MaxBackoff {
+			backoff = w.MaxBackoff
+		}
+	}
+
+	w.disconnect()
+}
+
+func (w *FluentWriter) disconnect() {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+		w.reader = nil
+	}
+}
+
+// send sends events and waits for an ack if required.
+func (w *FluentWriter) send(events []fluentEvent) error {
+	if w.conn == nil {
+		c, err := net.DialTimeout(w.Network, w.Address, dialTimeout)
+		if err != nil {
+			return err
+		}
+		w.conn = c
+		w.reader = bufio.NewReader(c)
+	}
+
+	var chunk string
+	if w.RequireAck {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+	}
+
+	msg, err := w.encode(events, chunk)
+	if err != nil {
+		return err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return err
+	}
+	if !w.RequireAck {
+		return nil
+	}
+
+	w.conn.SetReadDeadline(time.Now().Add(w.AckTimeout))
+	d := msgpackDecoder{w.reader}
+	resp, err := d.decode()
+	if err != nil {
+		return err
+	}
+	w.conn.SetReadDeadline(time.Time{})
+	m, ok := resp.(map[string]interface{})
+	if !ok || m["ack"] != chunk {
+		return fmt.Errorf("FluentWriter: unexpected response: %v", resp)
+	}
+	return nil
+}
+
+// encode encodes events in a message of w.Mode.
+func (w *FluentWriter) encode(events []fluentEvent, chunk string) ([]byte, error) {
+	var err error
+	b := make([]byte, 0, 4096)
+
+	if w.Mode == FluentMessage {
+		ev := events[0]
+		if len(chunk) == 0 {
+			b = append(b, mpFixArray+3)
+		} else {
+			b = append(b, mpFixArray+4)
+		}
+		b, err = appendMsgpackString(b, ev.tag)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, ev.entry...)
+		if len(chunk) == 0 {
+			return b, nil
+		}
+		return appendFluentOption(b, chunk, 0)
+	}
+
+	b = append(b, mpFixArray+3)
+	b, err = appendMsgpackString(b, events[0].tag)
+	if err != nil {
+		return nil, err
+	}
+
+	switch w.Mode {
+	case FluentForward:
+		b, err = appendMsgpackArray(b, len(events))
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range events {
+			b = append(b, mpFixArray+2)
+			b = append(b, ev.entry...)
+		}
+	case FluentPackedForward:
+		var stream []byte
+		for _, ev := range events {
+			stream = append(stream, mpFixArray+2)
+			stream = append(stream, ev.entry...)
+		}
+		b, err = appendMsgpack(b, stream)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("FluentWriter: invalid mode")
+	}
+
+	return appendFluentOption(b, chunk, len(events))
+}
+
+// appendFluentOption appends the option map.
+// chunk is omitted if empty, and size is omitted if zero.
+func appendFluentOption(b []byte, chunk string, size int) ([]byte, error) {
+	var err error
+
+	n := 0
+	if len(chunk) > 0 {
+		n++
+	}
+	if size > 0 {
+		n++
+	}
+	b = append(b, byte(mpFixMap+n))
+	if len(chunk) > 0 {
+		b, err = appendMsgpackString(b, "chunk")
+		if err != nil {
+			return nil, err
+		}
+		b, err = appendMsgpackString(b, chunk)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if size > 0 {
+		b, err = appendMsgpackString(b, "size")
+		if err != nil {
+			return nil, err
+		}
+		b = appendMsgpackInt64(b, int64(size))
+	}
+	return b, nil
+}
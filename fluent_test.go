@@ -0,0 +1,240 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fluentServer is a stub server of Fluentd Forward protocol.
+type fluentServer struct {
+	ln net.Listener
+
+	// number of messages to discard without ack at the beginning.
+	discard int
+
+	mu       sync.Mutex
+	messages [][]interface{}
+}
+
+func newFluentServer(t *testing.T, discard int) *fluentServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fluentServer{ln: ln, discard: discard}
+	go s.serve()
+	return s
+}
+
+func (s *fluentServer) serve() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+func (s *fluentServer) handle(c net.Conn) {
+	defer c.Close()
+	d := msgpackDecoder{bufio.NewReader(c)}
+	for {
+		v, err := d.decode()
+		if err != nil {
+			return
+		}
+		msg := v.([]interface{})
+
+		s.mu.Lock()
+		if s.discard > 0 {
+			s.discard--
+			s.mu.Unlock()
+			return
+		}
+		s.messages = append(s.messages, msg)
+		s.mu.Unlock()
+
+		opt, ok := msg[len(msg)-1].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		chunk, ok := opt["chunk"].(string)
+		if !ok {
+			continue
+		}
+		resp, _ := appendMsgpackString([]byte{mpFixMap + 1}, "ack")
+		resp, _ = appendMsgpackString(resp, chunk)
+		if _, err := c.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fluentServer) Messages() [][]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+func (s *fluentServer) Close() {
+	s.ln.Close()
+}
+
+func testFluentLogger(w *FluentWriter) *Logger {
+	l := NewLogger()
+	l.SetTopic("tag")
+	l.SetOutput(w)
+	l.SetFormatter(MsgPack{Utsname: "localhost", EventTime: true})
+	return l
+}
+
+func TestFluentWriterMessage(t *testing.T) {
+	t.Parallel()
+
+	s := newFluentServer(t, 1)
+	defer s.Close()
+
+	w := &FluentWriter{
+		Address:    s.ln.Addr().String(),
+		RequireAck: true,
+		MinBackoff: 10 * time.Millisecond,
+	}
+	l := testFluentLogger(w)
+	for _, msg := range []string{"first", "second"} {
+		if err := l.Error(msg, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// the first try fails as the server discards the message.
+	if err := w.Flush(ctx); err == nil {
+		t.Error("Flush should report the failure")
+	}
+	if err := w.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := s.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("2 messages are expected but %d", len(msgs))
+	}
+	for i, expected := range []string{"first", "second"} {
+		msg := msgs[i]
+		if len(msg) != 4 {
+			t.Fatalf("message should have 4 elements: %v", msg)
+		}
+		if msg[0] != "tag" {
+			t.Errorf("unexpected tag: %v", msg[0])
+		}
+		if _, ok := msg[1].(time.Time); !ok {
+			t.Errorf("time should be EventTime: %#v", msg[1])
+		}
+		record := msg[2].(map[string]interface{})
+		if record[FnMessage] != expected {
+			t.Errorf("unexpected record: %v", record)
+		}
+	}
+}
+
+func TestFluentWriterForward(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []FluentMode{FluentForward, FluentPackedForward} {
+		s := newFluentServer(t, 0)
+
+		w := &FluentWriter{
+			Address:    s.ln.Addr().String(),
+			Mode:       mode,
+			RequireAck: mode == FluentPackedForward,
+			BatchSize:  10,
+		}
+		l := testFluentLogger(w)
+		for i := 0; i < 25; i++ {
+			if err := l.Info("hello", map[string]interface{}{"i": i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// without ack, the server may not have received messages yet.
+		var msgs [][]interface{}
+		for i := 0; i < 100; i++ {
+			msgs = s.Messages()
+			var total int64
+			for _, msg := range msgs {
+				total += msg[2].(map[string]interface{})["size"].(int64)
+			}
+			if total == 25 {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		s.Close()
+
+		var records []map[string]interface{}
+		for _, msg := range msgs {
+			if len(msg) != 3 {
+				t.Fatalf("message should have 3 elements: %v", msg)
+			}
+			opt := msg[2].(map[string]interface{})
+
+			var entries []interface{}
+			switch mode {
+			case FluentForward:
+				entries = msg[1].([]interface{})
+			case FluentPackedForward:
+				d := msgpackDecoder{bytes.NewReader(msg[1].([]byte))}
+				for {
+					v, err := d.decode()
+					if err != nil {
+						break
+					}
+					entries = append(entries, v)
+				}
+				if _, ok := opt["chunk"]; !ok {
+					t.Error("chunk option should be set")
+				}
+			}
+			if len(entries) > 10 {
+				t.Errorf("too many entries: %d", len(entries))
+			}
+			if opt["size"] != int64(len(entries)) {
+				t.Errorf("size option should be %d: %v", len(entries), opt)
+			}
+			for _, e := range entries {
+				records = append(records, e.([]interface{})[1].(map[string]interface{}))
+			}
+		}
+
+		if len(records) != 25 {
+			t.Fatalf("mode %d: 25 records are expected but %d", mode, len(records))
+		}
+		for i, r := range records {
+			if r["i"] != int64(i) {
+				t.Errorf("mode %d: unexpected record: %v", mode, r)
+			}
+		}
+	}
+}
+
+func TestFluentWriterInvalid(t *testing.T) {
+	t.Parallel()
+
+	w := &FluentWriter{}
+	if _, err := w.Write([]byte("hoge\n")); err != ErrInvalidData {
+		t.Errorf("got %v, want ErrInvalidData", err)
+	}
+}
@@ -1,14 +1,37 @@
 package log
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
+	"sync"
 	"time"
 )
 
 var (
 	regexpValidKey = regexp.MustCompile(`^[_a-z][a-z0-9_]*$`)
+
+	formattersMu sync.RWMutex
+	formatters   = defaultFormatters()
 )
 
+// defaultFormatters returns the formatters registered by default.
+func defaultFormatters() map[string]FormatterFactory {
+	m := map[string]FormatterFactory{
+		"plain":   func(utsname string) Formatter { return PlainFormat{Utsname: utsname} },
+		"logfmt":  func(utsname string) Formatter { return Logfmt{Utsname: utsname} },
+		"json":    func(utsname string) Formatter { return JSONFormat{Utsname: utsname} },
+		"msgpack": func(utsname string) Formatter { return MsgPack{Utsname: utsname} },
+		"syslog":  func(utsname string) Formatter { return SyslogFormat{Utsname: utsname} },
+		"gelf":    func(utsname string) Formatter { return GELFFormat{Utsname: utsname} },
+		"console": func(string) Formatter { return ConsoleFormat{LocalTime: true} },
+	}
+	for name, factory := range platformFormatters {
+		m[name] = factory
+	}
+	return m
+}
+
 // Formatter is the interface for log formatters.
 type Formatter interface {
 	// Format appends formatted log data into buf.
@@ -35,3 +58,68 @@ func ReservedKey(k string) bool {
 func IsValidKey(key string) bool {
 	return regexpValidKey.MatchString(key) && !ReservedKey(key)
 }
+
+// FormatterFactory creates a Formatter.
+//
+// utsname is the hostname to be used in logs.  If empty, the formatter
+// should use the hostname of the system.  Factories for formatters
+// that do not output hostnames may ignore it.
+type FormatterFactory func(utsname string) Formatter
+
+// RegisterFormatter makes a formatter available by name for
+// FormatterByName, ConfigureFromEnv, and Config.
+//
+// "plain", "logfmt", "json", "msgpack", "syslog", "gelf", and "console"
+// are registered by default, as well as "journal" on Linux.
+//
+// The default logger is configured by ConfigureFromEnv during the
+// initialization of this package, so formatters registered by other
+// packages are not available for the default logger at that time.
+// Call ConfigureFromEnv again after registration to use them.
+//
+// RegisterFormatter panics if name is empty, factory is nil,
+// or name is already registered.
+func RegisterFormatter(name string, factory FormatterFactory) {
+	if len(name) == 0 {
+		panic("log: empty formatter name")
+	}
+	if factory == nil {
+		panic("log: nil formatter factory for " + name)
+	}
+
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	if _, ok := formatters[name]; ok {
+		panic("log: formatter is already registered: " + name)
+	}
+	formatters[name] = factory
+}
+
+// FormatterByName returns a new formatter registered as name.
+func FormatterByName(name string) (Formatter, error) {
+	return newFormatter(name, "")
+}
+
+// FormatterNames returns the sorted names of registered formatters.
+func FormatterNames() []string {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newFormatter returns a new formatter registered as name with utsname.
+func newFormatter(name, utsname string) (Formatter, error) {
+	formattersMu.RLock()
+	factory, ok := formatters[name]
+	formattersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no such formatter: %s", name)
+	}
+	return factory(utsname), nil
+}
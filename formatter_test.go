@@ -0,0 +1,64 @@
+package log
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+type upperFormat struct {
+	utsname string
+}
+
+func (f upperFormat) String() string {
+	return "upper"
+}
+
+func (f upperFormat) Format(buf []byte, l *Logger, t time.Time, severity int,
+	msg string, fields map[string]interface{}) ([]byte, error) {
+	return append(buf, f.utsname+": "+msg+"\n"...), nil
+}
+
+func TestFormatterByName(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"plain", "logfmt", "json", "msgpack", "syslog", "gelf", "console"}
+	if runtime.GOOS == "linux" {
+		names = append(names, "journal")
+	}
+	for _, name := range names {
+		f, err := FormatterByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.String() != name {
+			t.Errorf("unexpected formatter for %s: %s", name, f)
+		}
+	}
+
+	if _, err := FormatterByName("upper"); err == nil {
+		t.Error("upper should not be registered yet")
+	}
+	RegisterFormatter("upper", func(utsname string) Formatter { return upperFormat{utsname: utsname} })
+	t.Cleanup(func() {
+		formattersMu.Lock()
+		delete(formatters, "upper")
+		formattersMu.Unlock()
+	})
+	f, err := newFormatter("upper", "myhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.(upperFormat).utsname != "myhost" {
+		t.Error("utsname is not passed to the factory")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("duplicate registration should panic")
+			}
+		}()
+		RegisterFormatter("json", func(string) Formatter { return JSONFormat{} })
+	}()
+}
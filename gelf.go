@@ -0,0 +1,264 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// gelfChunkSize is the maximum size of UDP datagrams.
+	// 1420 bytes fits in typical MTUs of WAN.
+	gelfChunkSize = 1420
+
+	// gelfChunkHeaderSize is the size of the header of chunks:
+	// magic bytes, message ID, sequence number, and sequence count.
+	gelfChunkHeaderSize = 2 + 8 + 1 + 1
+
+	// gelfMaxChunks is the maximum number of chunks of a message.
+	gelfMaxChunks = 128
+)
+
+// GELFFormat implements Formatter for GELF (Graylog Extended Log Format)
+// version 1.1.
+//
+// The message is stored in short_message, the hostname in host,
+// the severity in level, and the topic in _topic.  Extra fields are
+// stored in fields named by prefixing the keys with an underscore.
+// As GELF does not allow _id, "id" field is stored in __id.
+//
+// GELF accepts only strings and numbers as field values.  Booleans are
+// converted to strings, and nil values are omitted.  Other values such
+// as maps and slices are encoded in JSON and stored as strings.
+//
+// Use NewGELFWriter to send formatted messages to GELF servers.
+type GELFFormat struct {
+	// Utsname can normally be left blank.
+	// If not empty, the string is used instead of the hostname.
+	// Utsname must match this regexp: ^[a-z][a-z0-9-]*$
+	Utsname string
+}
+
+// String returns "gelf".
+func (f GELFFormat) String() string {
+	return "gelf"
+}
+
+// Format implements Formatter.Format.
+func (f GELFFormat) Format(buf []byte, l *Logger, t time.Time, severity int,
+	msg string, fields map[string]interface{}) ([]byte, error) {
+	var err error
+
+	switch {
+	case severity < 0:
+		severity = 0
+	case severity > LvDebug:
+		severity = LvDebug
+	}
+
+	buf = append(buf, `{"version":"1.1","host":"`...)
+	if len(f.Utsname) > 0 {
+		buf = append(buf, f.Utsname...)
+	} else {
+		buf = append(buf, utsname...)
+	}
+	buf = append(buf, `","short_message":`...)
+	buf = appendString(buf, msg)
+	buf = append(buf, `,"timestamp":`...)
+	buf = strconv.AppendInt(buf, t.Unix(), 10)
+	buf = append(buf, '.')
+	usec := t.Nanosecond() / 1000
+	for d := 100000; d > 1 && usec < d; d /= 10 {
+		buf = append(buf, '0')
+	}
+	buf = strconv.AppendInt(buf, int64(usec), 10)
+	buf = append(buf, `,"level":`...)
+	buf = strconv.AppendInt(buf, int64(severity), 10)
+	buf = append(buf, `,"_topic":`...)
+	buf = appendString(buf, l.Topic())
+
+	for k, v := range fields {
+		if !IsValidKey(k) {
+			return nil, ErrInvalidKey
+		}
+		buf, err = appendGELFField(buf, k, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for k, v := range l.Defaults() {
+		if _, ok := fields[k]; ok {
+			continue
+		}
+		buf, err = appendGELFField(buf, k, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return append(buf, "}\n"...), nil
+}
+
+func appendGELFField(buf []byte, k string, v interface{}) ([]byte, error) {
+	if v == nil {
+		return buf, nil
+	}
+
+	buf = append(buf, `,"_`...)
+	if k == "id" {
+		buf = append(buf, '_')
+	}
+	buf = append(buf, k...)
+	buf = append(buf, `":`...)
+
+	switch t := v.(type) {
+	case bool:
+		return appendString(buf, strconv.FormatBool(t)), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, string:
+		return appendJSON(buf, v)
+	}
+
+	s, err := appendJSON(nil, v)
+	if err != nil {
+		return nil, err
+	}
+	if len(s) > 0 && s[0] == '"' {
+		return append(buf, s...), nil
+	}
+	return appendString(buf, string(s)), nil
+}
+
+// GELFCompression is the compression method of GELF messages over UDP.
+type GELFCompression int
+
+// Compression methods of GELF messages.
+const (
+	GELFNoCompression GELFCompression = iota
+	GELFZlib
+	GELFGzip
+)
+
+type gelfWriter struct {
+	*netWriter
+	stream      bool
+	compression GELFCompression
+}
+
+// NewGELFWriter connects to a GELF server and returns an io.WriteCloser
+// to send logs formatted by GELFFormat.
+//
+// network and addr are passed to net.Dial.  For stream networks ("tcp",
+// "tcp4", "tcp6", and "unix"), messages are terminated by a null byte.
+// For other networks, messages are compressed by compression and sent
+// as datagrams.  Messages larger than 1420 bytes are split into chunks.
+// Compression is not available for stream networks as GELF does not
+// support it.
+//
+// If sending fails, the writer reconnects to the server and retries once.
+func NewGELFWriter(network, addr string, compression GELFCompression) (io.WriteCloser, error) {
+	w := &gelfWriter{compression: compression}
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		w.stream = true
+	}
+	switch {
+	case compression < GELFNoCompression || compression > GELFGzip:
+		return nil, errors.New("unknown GELF compression")
+	case w.stream && compression != GELFNoCompression:
+		return nil, errors.New("GELF compression is not supported for " + network)
+	}
+
+	nw, err := dialNetWriter(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	w.netWriter = nw
+	return w, nil
+}
+
+// Write sends p as a GELF message.  A trailing newline is removed.
+func (w *gelfWriter) Write(p []byte) (int, error) {
+	msg := p
+	if len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+	}
+
+	if w.stream {
+		b := make([]byte, 0, len(msg)+1)
+		b = append(b, msg...)
+		b = append(b, 0)
+		if _, err := w.netWriter.Write(b); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	msg, err := w.compress(msg)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.writeChunks(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *gelfWriter) compress(msg []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var c io.WriteCloser
+	switch w.compression {
+	case GELFZlib:
+		c = zlib.NewWriter(&buf)
+	case GELFGzip:
+		c = gzip.NewWriter(&buf)
+	default:
+		return msg, nil
+	}
+	if _, err := c.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := c.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeChunks sends msg as a datagram, or as chunks if msg is too large.
+func (w *gelfWriter) writeChunks(msg []byte) error {
+	if len(msg) <= gelfChunkSize {
+		_, err := w.netWriter.Write(msg)
+		return err
+	}
+
+	const dataSize = gelfChunkSize - gelfChunkHeaderSize
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return ErrTooLarge
+	}
+
+	chunk := make([]byte, gelfChunkHeaderSize, gelfChunkSize)
+	chunk[0] = 0x1e
+	chunk[1] = 0x0f
+	if _, err := io.ReadFull(rand.Reader, chunk[2:10]); err != nil {
+		return err
+	}
+	chunk[11] = byte(count)
+	for i := 0; i < count; i++ {
+		chunk[10] = byte(i)
+		data := msg[i*dataSize:]
+		if len(data) > dataSize {
+			data = data[:dataSize]
+		}
+		if _, err := w.netWriter.Write(append(chunk[:gelfChunkHeaderSize], data...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFFormat(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("gelftest")
+	l.SetDefaults(map[string]interface{}{"env": "dev"})
+	loggedAt := time.Date(2001, 12, 3, 13, 45, 1, 5000, time.UTC)
+
+	f := GELFFormat{Utsname: "host1"}
+	b, err := f.Format(nil, l, loggedAt, LvWarn, "hello", map[string]interface{}{
+		"id":     "abc",
+		"count":  3,
+		"ok":     true,
+		"none":   nil,
+		"tags":   []string{"x", "y"},
+		"remote": net.IPv4(10, 0, 0, 1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(b, []byte("}\n")) {
+		t.Errorf("unexpected message: %q", b)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"version":       "1.1",
+		"host":          "host1",
+		"short_message": "hello",
+		"timestamp":     1007387101.000005,
+		"level":         float64(LvWarn),
+		"_topic":        "gelftest",
+		"__id":          "abc",
+		"_count":        float64(3),
+		"_ok":           "true",
+		"_tags":         `["x","y"]`,
+		"_remote":       "10.0.0.1",
+		"_env":          "dev",
+	}
+	if len(m) != len(expected) {
+		t.Errorf("unexpected message: %s", b)
+	}
+	for k, v := range expected {
+		if m[k] != v {
+			t.Errorf("unexpected %s: %#v", k, m[k])
+		}
+	}
+	if !bytes.Contains(b, []byte(`"timestamp":1007387101.000005,`)) {
+		t.Errorf("unexpected timestamp: %s", b)
+	}
+
+	if _, err := f.Format(nil, l, loggedAt, LvInfo, "", map[string]interface{}{"Bad": 1}); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
+
+// readGELFUDP reads a GELF message from c reassembling chunks.
+func readGELFUDP(t *testing.T, c net.PacketConn) []byte {
+	t.Helper()
+
+	var chunks [][]byte
+	var id []byte
+	for received := 0; ; {
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 65536)
+		n, _, err := c.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = b[:n]
+		if n > gelfChunkSize {
+			t.Fatalf("too large datagram: %d", n)
+		}
+		if !bytes.HasPrefix(b, []byte{0x1e, 0x0f}) {
+			return b
+		}
+
+		if chunks == nil {
+			chunks = make([][]byte, b[11])
+			id = b[2:10]
+		}
+		if !bytes.Equal(id, b[2:10]) || int(b[11]) != len(chunks) {
+			t.Fatalf("inconsistent chunk header: %x", b[:gelfChunkHeaderSize])
+		}
+		chunks[b[10]] = b[gelfChunkHeaderSize:]
+		received++
+		if received == len(chunks) {
+			return bytes.Join(chunks, nil)
+		}
+	}
+}
+
+func TestGELFWriterUDP(t *testing.T) {
+	t.Parallel()
+
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// random data to be chunked even if compressed.
+	var ids []string
+	for i := 0; i < 200; i++ {
+		ids = append(ids, newRequestID())
+	}
+	large := strings.Join(ids, " ")
+	decompress := map[GELFCompression]func(io.Reader) (io.Reader, error){
+		GELFNoCompression: func(r io.Reader) (io.Reader, error) { return r, nil },
+		GELFZlib:          func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		GELFGzip:          func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+
+	for compression, dec := range decompress {
+		w, err := NewGELFWriter("udp", c.LocalAddr().String(), compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l := NewLogger()
+		l.SetOutput(w)
+		l.SetFormatter(GELFFormat{})
+		for _, msg := range []string{"small", large} {
+			if err := l.Error(msg, map[string]interface{}{"random": newRequestID()}); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := dec(bytes.NewReader(readGELFUDP(t, c)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m map[string]interface{}
+			if err := json.NewDecoder(r).Decode(&m); err != nil {
+				t.Fatal(err)
+			}
+			if m["short_message"] != msg || m["level"] != float64(LvError) {
+				t.Errorf("unexpected message: %v", m)
+			}
+		}
+		w.Close()
+	}
+
+	w, err := NewGELFWriter("udp", c.LocalAddr().String(), GELFNoCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write(make([]byte, gelfChunkSize*gelfMaxChunks)); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestGELFWriterTCP(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if _, err := NewGELFWriter("tcp", ln.Addr().String(), GELFGzip); err == nil {
+		t.Error("compression should not be allowed for TCP")
+	}
+
+	w, err := NewGELFWriter("tcp", ln.Addr().String(), GELFNoCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	l := NewLogger()
+	l.SetOutput(w)
+	l.SetFormatter(GELFFormat{})
+	l.Error("hello world", nil)
+	l.Error("second\nline", nil)
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(c)
+
+	for _, expected := range []string{"hello world", "second\nline"} {
+		msg, err := r.ReadBytes(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(msg[:len(msg)-1], &m); err != nil {
+			t.Fatal(err)
+		}
+		if m["short_message"] != expected {
+			t.Errorf("unexpected message: %q", msg)
+		}
+	}
+}
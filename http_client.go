@@ -0,0 +1,161 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// redactedValue replaces query parameter values when redaction is enabled.
+const redactedValue = "REDACTED"
+
+// LogTransport is an http.RoundTripper that wraps Transport to output
+// a log for each HTTP client request.  Logs have these fields:
+//
+//	type=http, response_time, url, protocol, http_method, http_host,
+//	http_status_code, request_size, response_size, error.
+//
+// If the request context has a request ID stored by WithRequestID,
+// it is sent in X-Request-ID header unless the header is already set.
+//
+// The log is output when the response body is read to EOF or closed,
+// so response_time and response_size include the transfer of the body.
+// If the request fails, the log is output immediately with error field.
+// For 101 Switching Protocols responses, the log is also output
+// immediately without response_size, and the body is returned as is
+// so that it can be used as io.ReadWriteCloser.
+type LogTransport struct {
+	// Transport is the underlying http.RoundTripper.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Logger is used to output logs.
+	// If nil, the logger in the request context is used.
+	Logger *Logger
+
+	// Severity returns the severity of the log for a response or
+	// an error.  If nil, LvError is used for errors and 5xx,
+	// LvWarn for 4xx, and LvInfo for others.
+	Severity func(resp *http.Response, err error) int
+
+	// RedactQuery replaces values of query parameters in url field
+	// with "REDACTED".  Passwords in URLs are always redacted.
+	RedactQuery bool
+}
+
+// defaultClientSeverity returns the severity for resp and err.
+func defaultClientSeverity(resp *http.Response, err error) int {
+	if err != nil {
+		return LvError
+	}
+	return defaultAccessSeverity(resp.StatusCode)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *LogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	startAt := time.Now()
+
+	if id := RequestIDFromContext(req.Context()); len(id) > 0 && len(req.Header.Get(RequestIDHeader)) == 0 {
+		// RoundTripper must not modify the request.
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.log(req, nil, err, 0, startAt)
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		t.log(req, resp, nil, -1, startAt)
+		return resp, nil
+	}
+
+	body := &logBody{ReadCloser: resp.Body}
+	body.log = func() {
+		t.log(req, resp, nil, body.n, startAt)
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// log outputs a log.  respSize is omitted if negative.
+func (t *LogTransport) log(req *http.Request, resp *http.Response, err error, respSize int64, startAt time.Time) {
+	u := *req.URL
+	if t.RedactQuery && len(u.RawQuery) > 0 {
+		q := u.Query()
+		for _, values := range q {
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	host := req.Host
+	if len(host) == 0 {
+		host = u.Host
+	}
+
+	fields := map[string]interface{}{
+		FnType:         "http",
+		FnResponseTime: time.Since(startAt).Seconds(),
+		FnURL:          u.Redacted(),
+		FnHTTPMethod:   req.Method,
+		FnHTTPHost:     host,
+	}
+	if req.ContentLength > 0 {
+		fields[FnRequestSize] = req.ContentLength
+	}
+	if resp != nil {
+		fields[FnProtocol] = resp.Proto
+		fields[FnHTTPStatusCode] = resp.StatusCode
+		if respSize >= 0 {
+			fields[FnResponseSize] = respSize
+		}
+	}
+	if err != nil {
+		fields[FnError] = err.Error()
+	}
+
+	severity := defaultClientSeverity
+	if t.Severity != nil {
+		severity = t.Severity
+	}
+
+	l := t.Logger
+	if l == nil {
+		l = FromContext(req.Context())
+	}
+	l.LogContext(req.Context(), severity(resp, err), "http", fields)
+}
+
+// logBody counts bytes read from a response body and calls log
+// once at EOF or Close.
+type logBody struct {
+	io.ReadCloser
+	n    int64
+	once sync.Once
+	log  func()
+}
+
+func (b *logBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF {
+		b.once.Do(b.log)
+	}
+	return n, err
+}
+
+func (b *logBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.log)
+	return err
+}
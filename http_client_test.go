@@ -0,0 +1,183 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogTransport(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(RequestIDHeader, r.Header.Get(RequestIDHeader))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("error!"))
+	}))
+	defer s.Close()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	client := &http.Client{
+		Transport: &LogTransport{
+			Logger:      l,
+			RedactQuery: true,
+		},
+	}
+	ctx := WithRequestID(context.Background(), "1234")
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL+"/path?token=secret", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Header.Get(RequestIDHeader)) != 0 {
+		t.Error("the request should not be modified")
+	}
+	if resp.Header.Get(RequestIDHeader) != "1234" {
+		t.Error("request ID should be sent")
+	}
+	if buf.Len() != 0 {
+		t.Error("log should be output after reading the body")
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		FnSeverity:       "error",
+		FnType:           "http",
+		FnRequestID:      "1234",
+		FnURL:            s.URL + "/path?token=REDACTED",
+		FnProtocol:       "HTTP/1.1",
+		FnHTTPMethod:     "POST",
+		FnHTTPHost:       strings.TrimPrefix(s.URL, "http://"),
+		FnHTTPStatusCode: 500.0,
+		FnRequestSize:    5.0,
+		FnResponseSize:   6.0,
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("%s: got %v, want %v", k, record[k], v)
+		}
+	}
+	if _, ok := record[FnResponseTime].(float64); !ok {
+		t.Error("response_time should be set")
+	}
+}
+
+func TestLogTransportError(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	var gotErr error
+	client := &http.Client{
+		Transport: &LogTransport{
+			Logger: l,
+			Severity: func(resp *http.Response, err error) int {
+				gotErr = err
+				return LvWarn
+			},
+		},
+	}
+	if _, err := client.Get(s.URL); err == nil {
+		t.Fatal("request should fail")
+	}
+	if gotErr == nil {
+		t.Error("Severity should receive the error")
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[FnSeverity] != "warning" || record[FnError] == nil {
+		t.Errorf("unexpected record: %v", record)
+	}
+	if _, ok := record[FnHTTPStatusCode]; ok {
+		t.Errorf("status code should not be set: %v", record)
+	}
+}
+
+func TestLogTransportSwitchingProtocols(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString(line)
+		rw.Flush()
+	}))
+	defer s.Close()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	client := &http.Client{Transport: &LogTransport{Logger: l}}
+	req, err := http.NewRequest("GET", s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal("log should be output immediately:", err)
+	}
+	if record[FnHTTPStatusCode] != 101.0 {
+		t.Errorf("unexpected status: %v", record[FnHTTPStatusCode])
+	}
+	if _, ok := record[FnResponseSize]; ok {
+		t.Error("response_size should not be set")
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatal("body should be io.ReadWriteCloser")
+	}
+	if _, err := rwc.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 6)
+	if _, err := io.ReadFull(rwc, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello\n" {
+		t.Errorf("unexpected echo: %q", got)
+	}
+}
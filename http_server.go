@@ -0,0 +1,324 @@
+package log
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// RequestIDHeader is the HTTP header to propagate request IDs.
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength is the maximum length of request IDs
+	// accepted from clients.
+	maxRequestIDLength = 128
+)
+
+// AccessLog is an http.Handler that wraps Handler to output an access log
+// for each request.  Access logs have these fields:
+//
+//	type=access, request_id, response_time, remote_ipaddr, url, protocol,
+//	http_method, http_host, http_status_code, http_referer,
+//	http_user_agent, request_size, response_size.
+//
+// The request ID is taken from X-Request-ID header of the request,
+// or generated as a UUID if the header is missing or invalid.
+// The ID is set to X-Request-ID header of the request and the response,
+// and stored in the request context by WithRequestID.
+type AccessLog struct {
+	// Handler is the handler to be wrapped.
+	Handler http.Handler
+
+	// Logger is used to output access logs.  If Logger is not nil,
+	// it is also stored in the request context by WithLogger.
+	// If nil, the logger in the request context is used.
+	Logger *Logger
+
+	// Severity returns the severity of the access log for an HTTP
+	// status code.  If nil, LvError is used for 5xx, LvWarn for 4xx,
+	// and LvInfo for others.
+	Severity func(status int) int
+
+	// Fields, if not nil, is called to add fields to the access log.
+	Fields func(r *http.Request, fields map[string]interface{})
+}
+
+// defaultAccessSeverity returns the severity for status.
+func defaultAccessSeverity(status int) int {
+	switch {
+	case status >= 500:
+		return LvError
+	case status >= 400:
+		return LvWarn
+	default:
+		return LvInfo
+	}
+}
+
+// newRequestID generates a random (version 4) UUID.
+func newRequestID() string {
+	var u [16]byte
+	if _, err := io.ReadFull(rand.Reader, u[:]); err != nil {
+		panic(err)
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// isValidRequestID returns true if id can be used as a request ID.
+func isValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP implements http.Handler.
+func (h *AccessLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	startAt := time.Now()
+
+	id := r.Header.Get(RequestIDHeader)
+	if !isValidRequestID(id) {
+		id = newRequestID()
+		r.Header.Set(RequestIDHeader, id)
+	}
+	w.Header().Set(RequestIDHeader, id)
+
+	ctx := WithRequestID(r.Context(), id)
+	if h.Logger != nil {
+		ctx = WithLogger(ctx, h.Logger)
+	}
+	r = r.WithContext(ctx)
+
+	body := &countReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	rw := &responseWriter{ResponseWriter: w}
+
+	defer func() {
+		err := recover()
+		if err != nil && err != http.ErrAbortHandler && rw.status == 0 {
+			rw.status = http.StatusInternalServerError
+		}
+		h.log(r, rw, body.n, startAt)
+		if err != nil {
+			panic(err)
+		}
+	}()
+	h.Handler.ServeHTTP(rw.wrap(), r)
+}
+
+func (h *AccessLog) log(r *http.Request, rw *responseWriter, reqSize int64, startAt time.Time) {
+	status := rw.status
+	switch {
+	case rw.hijacked && status == 0:
+		status = http.StatusSwitchingProtocols
+	case status == 0:
+		status = http.StatusOK
+	}
+
+	fields := map[string]interface{}{
+		FnType:           "access",
+		FnResponseTime:   time.Since(startAt).Seconds(),
+		FnRemoteAddress:  remoteIP(r),
+		FnURL:            r.RequestURI,
+		FnProtocol:       r.Proto,
+		FnHTTPMethod:     r.Method,
+		FnHTTPHost:       r.Host,
+		FnHTTPStatusCode: status,
+		FnRequestSize:    reqSize,
+		FnResponseSize:   rw.size,
+	}
+	if referer := r.Referer(); len(referer) > 0 {
+		fields[FnHTTPReferer] = referer
+	}
+	if ua := r.UserAgent(); len(ua) > 0 {
+		fields[FnHTTPUserAgent] = ua
+	}
+	if h.Fields != nil {
+		h.Fields(r, fields)
+	}
+
+	severity := defaultAccessSeverity
+	if h.Severity != nil {
+		severity = h.Severity
+	}
+
+	l := h.Logger
+	if l == nil {
+		l = FromContext(r.Context())
+	}
+	l.LogContext(r.Context(), severity(status), "access", fields)
+}
+
+// remoteIP returns the IP address of the client.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// countReader counts bytes read from io.ReadCloser.
+type countReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// responseWriter records the status code and the size of a response.
+//
+// responseWriter itself does not implement http.Flusher, http.Hijacker,
+// nor http.Pusher.  Use wrap to obtain an http.ResponseWriter that
+// implements them only if the wrapped http.ResponseWriter does.
+// It implements Unwrap for http.ResponseController.
+type responseWriter struct {
+	http.ResponseWriter
+	status   int
+	size     int64
+	hijacked bool
+}
+
+type flusherFunc func()
+
+func (f flusherFunc) Flush() {
+	f()
+}
+
+type hijackerFunc func() (net.Conn, *bufio.ReadWriter, error)
+
+func (f hijackerFunc) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return f()
+}
+
+type pusherFunc func(target string, opts *http.PushOptions) error
+
+func (f pusherFunc) Push(target string, opts *http.PushOptions) error {
+	return f(target, opts)
+}
+
+// wrap returns an http.ResponseWriter that implements http.Flusher,
+// http.Hijacker, and http.Pusher as far as the wrapped
+// http.ResponseWriter implements them, so that handlers can correctly
+// detect the capabilities of the connection.
+func (w *responseWriter) wrap() http.ResponseWriter {
+	_, isFlusher := w.ResponseWriter.(http.Flusher)
+	_, isHijacker := w.ResponseWriter.(http.Hijacker)
+	_, isPusher := w.ResponseWriter.(http.Pusher)
+	f := flusherFunc(w.flush)
+	h := hijackerFunc(w.hijack)
+	p := pusherFunc(w.push)
+
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{w, f, h, p}
+	case isFlusher && isHijacker:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{w, f, h}
+	case isFlusher && isPusher:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+		}{w, f, p}
+	case isHijacker && isPusher:
+		return struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{w, h, p}
+	case isFlusher:
+		return struct {
+			*responseWriter
+			http.Flusher
+		}{w, f}
+	case isHijacker:
+		return struct {
+			*responseWriter
+			http.Hijacker
+		}{w, h}
+	case isPusher:
+		return struct {
+			*responseWriter
+			http.Pusher
+		}{w, p}
+	}
+	return w
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// informational responses other than 101 precede the final one.
+	informational := status >= 100 && status < 200 && status != http.StatusSwitchingProtocols
+	if w.status == 0 && !informational {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) push(target string, opts *http.PushOptions) error {
+	return w.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,224 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	var ctxID string
+	h := &AccessLog{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if FromContext(r.Context()) != l {
+				t.Error("logger should be stored in the context")
+			}
+			ctxID = RequestIDFromContext(r.Context())
+			io.Copy(io.Discard, r.Body)
+			if _, ok := w.(http.Flusher); !ok {
+				t.Error("http.Flusher should be implemented")
+			}
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}),
+		Logger: l,
+		Fields: func(r *http.Request, fields map[string]interface{}) {
+			fields["path"] = r.URL.Path
+		},
+	}
+
+	r := httptest.NewRequest("POST", "/abc?d=e", strings.NewReader("hello"))
+	r.Header.Set("User-Agent", "test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	id := w.Header().Get(RequestIDHeader)
+	if len(id) != 36 || id != ctxID {
+		t.Errorf("invalid request ID: %q, %q", id, ctxID)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		FnSeverity:       "warning",
+		FnType:           "access",
+		FnRequestID:      id,
+		FnRemoteAddress:  "192.0.2.1",
+		FnURL:            "/abc?d=e",
+		FnProtocol:       "HTTP/1.1",
+		FnHTTPMethod:     "POST",
+		FnHTTPHost:       "example.com",
+		FnHTTPStatusCode: 404.0,
+		FnHTTPUserAgent:  "test",
+		FnRequestSize:    5.0,
+		FnResponseSize:   9.0,
+		"path":           "/abc",
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("%s: got %v, want %v", k, record[k], v)
+		}
+	}
+	if _, ok := record[FnResponseTime].(float64); !ok {
+		t.Error("response_time should be set")
+	}
+}
+
+func TestAccessLogRequestID(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	h := &AccessLog{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		Logger:  l,
+		Severity: func(status int) int {
+			return LvError
+		},
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestIDHeader, "abcd-1234")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if id := w.Header().Get(RequestIDHeader); id != "abcd-1234" {
+		t.Errorf("request ID should be propagated: %q", id)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[FnSeverity] != "error" || record[FnHTTPStatusCode] != 200.0 {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+// chanWriter sends written data to the channel.
+type chanWriter chan []byte
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func TestAccessLogHijack(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	ch := make(chanWriter, 1)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+
+	h := &AccessLog{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+			rw.Flush()
+		}),
+		Logger: l,
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(<-ch, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[FnHTTPStatusCode] != 101.0 {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestAccessLogCapabilities(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetOutput(io.Discard)
+
+	// httptest.ResponseRecorder implements only http.Flusher.
+	h := &AccessLog{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := w.(http.Flusher); !ok {
+				t.Error("http.Flusher should be implemented")
+			}
+			if _, ok := w.(http.Hijacker); ok {
+				t.Error("http.Hijacker should not be implemented")
+			}
+			if _, ok := w.(http.Pusher); ok {
+				t.Error("http.Pusher should not be implemented")
+			}
+			if _, ok := w.(io.ReaderFrom); !ok {
+				t.Error("io.ReaderFrom should be implemented")
+			}
+			if _, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok {
+				t.Error("Unwrap should be implemented")
+			}
+			w.(http.Flusher).Flush()
+		}),
+		Logger: l,
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !w.Flushed {
+		t.Error("response should be flushed")
+	}
+}
+
+func TestAccessLogInformational(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+
+	h := &AccessLog{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", "</style.css>; rel=preload")
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusNotFound)
+		}),
+		Logger: l,
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[FnHTTPStatusCode] != 404.0 || record[FnSeverity] != "warning" {
+		t.Errorf("the final status should be logged: %v", record)
+	}
+}
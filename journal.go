@@ -0,0 +1,200 @@
+//go:build linux
+// +build linux
+
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultJournalSocket is the path of the native protocol socket
+	// of systemd-journald.
+	DefaultJournalSocket = "/run/systemd/journal/socket"
+)
+
+// platformFormatters are formatters registered by default on Linux.
+// This is a variable rather than init so that formatters are
+// registered before the default logger is configured.
+var platformFormatters = map[string]FormatterFactory{
+	"journal": func(string) Formatter { return JournalFormat{} },
+}
+
+// JournalFormat implements Formatter for the native protocol of
+// systemd-journald.
+//
+// The message is stored in MESSAGE, the severity in PRIORITY, and
+// the topic in SYSLOG_IDENTIFIER.  Extra fields are stored in fields
+// named by converting the keys to upper case.  Leading underscores
+// of keys are removed as such fields are reserved for journald.
+// FnCaller and FnFunction fields are stored in CODE_FILE, CODE_LINE,
+// and CODE_FUNC as journald recommends.
+//
+// To avoid conflicts with the above fields and invalid field names,
+// "FIELD_" is prefixed to names that are MESSAGE, PRIORITY, start with
+// CODE_ or SYSLOG_, or start with a digit.  For example, "priority"
+// field is stored in FIELD_PRIORITY and "_1st" in FIELD_1ST.
+//
+// Use NewJournalWriter to send formatted entries to journald.
+//
+// https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+type JournalFormat struct{}
+
+// String returns "journal".
+func (f JournalFormat) String() string {
+	return "journal"
+}
+
+// Format implements Formatter.Format.
+func (f JournalFormat) Format(buf []byte, l *Logger, t time.Time, severity int,
+	msg string, fields map[string]interface{}) ([]byte, error) {
+	var err error
+
+	buf = appendJournalField(buf, "MESSAGE", []byte(msg))
+	buf = append(buf, "PRIORITY="...)
+	buf = strconv.AppendInt(buf, int64(severity), 10)
+	buf = append(buf, '\n')
+	buf = append(buf, "SYSLOG_IDENTIFIER="...)
+	buf = append(buf, l.Topic()...)
+	buf = append(buf, '\n')
+
+	keys, values, err := mergeFields(l, fields)
+	if err != nil {
+		return nil, err
+	}
+	var tbuf []byte
+	for i, k := range keys {
+		name := journalFieldName(k)
+		switch k {
+		case FnCaller:
+			caller, ok := values[i].(string)
+			if idx := strings.LastIndexByte(caller, ':'); ok && idx != -1 {
+				buf = appendJournalField(buf, "CODE_FILE", []byte(caller[:idx]))
+				buf = appendJournalField(buf, "CODE_LINE", []byte(caller[idx+1:]))
+				continue
+			}
+		case FnFunction:
+			name = "CODE_FUNC"
+		}
+		if len(name) == 0 {
+			continue
+		}
+		tbuf, err = appendText(tbuf[:0], values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = appendJournalField(buf, name, tbuf)
+	}
+	return buf, nil
+}
+
+// journalFieldName converts a field key to a journal field name.
+func journalFieldName(k string) string {
+	name := strings.ToUpper(strings.TrimLeft(k, "_"))
+	switch {
+	case len(name) == 0:
+		return ""
+	case name == "MESSAGE", name == "PRIORITY",
+		strings.HasPrefix(name, "CODE_"), strings.HasPrefix(name, "SYSLOG_"),
+		name[0] >= '0' && name[0] <= '9':
+		return "FIELD_" + name
+	}
+	return name
+}
+
+// appendJournalField appends a field in the journal export format.
+// Values including newlines are serialized in the binary-safe form.
+func appendJournalField(buf []byte, name string, value []byte) []byte {
+	buf = append(buf, name...)
+	for _, b := range value {
+		if b == '\n' {
+			buf = append(buf, '\n')
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(len(value)))
+			buf = append(buf, value...)
+			return append(buf, '\n')
+		}
+	}
+	buf = append(buf, '=')
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+type journalWriter struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+// NewJournalWriter returns an io.WriteCloser to send entries formatted
+// by JournalFormat to systemd-journald listening on path.
+// If path is empty, DefaultJournalSocket is used.
+//
+// Each Write sends an entry as a datagram.  Entries too large for a
+// datagram are written in an unlinked temporary file on /dev/shm, and
+// its file descriptor is passed to journald instead.
+func NewJournalWriter(path string) (io.WriteCloser, error) {
+	if len(path) == 0 {
+		path = DefaultJournalSocket
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalWriter{
+		conn: conn,
+		addr: &net.UnixAddr{Name: path, Net: "unixgram"},
+	}, nil
+}
+
+// Write sends p as a journal entry.
+func (w *journalWriter) Write(p []byte) (int, error) {
+	_, _, err := w.conn.WriteMsgUnix(p, nil, w.addr)
+	if err == nil {
+		return len(p), nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return 0, err
+	}
+
+	f, err := journalTempFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Write(p); err != nil {
+		return 0, err
+	}
+	_, _, err = w.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), w.addr)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// journalTempFile creates an unlinked temporary file, preferably on tmpfs.
+func journalTempFile() (*os.File, error) {
+	f, err := os.CreateTemp("/dev/shm", "journal.*")
+	if err != nil {
+		f, err = os.CreateTemp("", "journal.*")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close closes the socket.
+func (w *journalWriter) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+// +build !linux
+
+package log
+
+// platformFormatters are formatters registered by default on Linux.
+var platformFormatters map[string]FormatterFactory
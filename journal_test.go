@@ -0,0 +1,171 @@
+//go:build linux
+// +build linux
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestJournalFormat(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("app")
+	l.SetDefaults(map[string]interface{}{
+		"_abc": 123,
+	})
+	buf := make([]byte, 0, 4096)
+
+	b, err := JournalFormat{}.Format(buf, l, time.Now(), LvWarn, "hello", map[string]interface{}{
+		"def": "multi\nline",
+		"ghi": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lenbuf [8]byte
+	binary.LittleEndian.PutUint64(lenbuf[:], 10)
+	expected := "MESSAGE=hello\nPRIORITY=4\nSYSLOG_IDENTIFIER=app\nABC=123\n" +
+		"DEF\n" + string(lenbuf[:]) + "multi\nline\nGHI=true\n"
+	if string(b) != expected {
+		t.Errorf("got %q, want %q", b, expected)
+	}
+
+	b, err = JournalFormat{}.Format(buf, l, time.Now(), LvWarn, "hello", map[string]interface{}{
+		FnCaller:   "log/journal_test.go:12",
+		FnFunction: "main.main",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "MESSAGE=hello\nPRIORITY=4\nSYSLOG_IDENTIFIER=app\nABC=123\n" +
+		"CODE_FILE=log/journal_test.go\nCODE_LINE=12\nCODE_FUNC=main.main\n"
+	if string(b) != expected {
+		t.Errorf("got %q, want %q", b, expected)
+	}
+
+	// names conflicting with the protocol or starting with a digit.
+	b, err = JournalFormat{}.Format(buf, NewLogger(), time.Now(), LvWarn, "hello", map[string]interface{}{
+		"_1st":              1,
+		"code_file":         "x.go",
+		"priority":          "low",
+		"syslog_identifier": "other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"\nFIELD_1ST=1\n", "\nFIELD_CODE_FILE=x.go\n",
+		"\nFIELD_PRIORITY=low\n", "\nFIELD_SYSLOG_IDENTIFIER=other\n"} {
+		if !bytes.Contains(b, []byte(field)) {
+			t.Errorf("%q should contain %q", b, field)
+		}
+	}
+	if bytes.Count(b, []byte("\nPRIORITY=")) != 1 || bytes.Count(b, []byte("\nSYSLOG_IDENTIFIER=")) != 1 {
+		t.Errorf("protocol fields should not be duplicated: %q", b)
+	}
+
+	if _, err := (JournalFormat{}).Format(buf, l, time.Now(), LvWarn, "hello", map[string]interface{}{
+		"Bad": 1,
+	}); err != ErrInvalidKey {
+		t.Errorf("got %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestJournalWriter(t *testing.T) {
+	t.Parallel()
+
+	sock := filepath.Join(t.TempDir(), "journal.sock")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.SetReadBuffer(1 << 20)
+
+	w, err := NewJournalWriter(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	l := NewLogger()
+	l.SetTopic("app")
+	l.SetOutput(w)
+	l.SetFormatter(JournalFormat{})
+
+	if err := l.Error("small", nil); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1<<20)
+	oob := make([]byte, 1024)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, _, _, err := server.ReadMsgUnix(data, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data[:n]), "MESSAGE=small\nPRIORITY=3\nSYSLOG_IDENTIFIER=app\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// large entries are passed via file descriptors.
+	large := strings.Repeat("x", 512*1024)
+	if err := l.Error(large, nil); err != nil {
+		t.Fatal(err)
+	}
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, _, _, err := server.ReadMsgUnix(data, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("datagram should be empty but %d bytes", n)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("one control message is expected but %d", len(msgs))
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(entry, []byte("MESSAGE="+large+"\n")) {
+		t.Error("unexpected entry")
+	}
+}
+
+func TestJournalFormatRegistered(t *testing.T) {
+	t.Parallel()
+
+	// journal must be registered before the default logger is
+	// configured in init of default.go.
+	factory, ok := defaultFormatters()["journal"]
+	if !ok {
+		t.Fatal("journal should be registered by default")
+	}
+	if _, ok := factory("").(JournalFormat); !ok {
+		t.Error("journal should create JournalFormat")
+	}
+}
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxLevelRequestSize is the maximum size of request bodies to LevelHandler.
+const maxLevelRequestSize = 4096
+
+// LevelHandler is an http.Handler to report and change the threshold
+// of a logger at runtime.
+//
+// GET returns the current threshold as JSON:
+//
+//	{"level": "info"}
+//
+// PUT changes the threshold.  The request body is JSON with the level
+// name accepted by Logger.SetThresholdByName and an optional TTL in
+// the format of time.ParseDuration:
+//
+//	{"level": "debug", "ttl": "10m"}
+//
+// If TTL is given, the previous threshold is restored after TTL.
+// While a restoration is pending, GET also returns the level to be
+// restored and the time of restoration:
+//
+//	{"level": "debug", "restore": "info", "expires_at": "2006-01-02T15:04:05Z"}
+//
+// Every change is audited by a log with "level_change" type regardless
+// of the thresholds of the logger and its sinks.
+type LevelHandler struct {
+	// Logger is the logger to be controlled.
+	// If nil, the default logger is used.
+	Logger *Logger
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	restore  int
+	expireAt time.Time
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+type levelResponse struct {
+	Level     string     `json:"level"`
+	Restore   string     `json:"restore,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (h *LevelHandler) logger() *Logger {
+	if h.Logger == nil {
+		return defaultLogger
+	}
+	return h.Logger
+}
+
+// levelString returns the name of level, or the number for undefined levels.
+func levelString(level int) string {
+	if name := LevelName(level); len(name) > 0 {
+		return name
+	}
+	return strconv.Itoa(level)
+}
+
+// ServeHTTP implements http.Handler.
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		if err := h.change(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.Lock()
+	resp := levelResponse{
+		Level: levelString(h.logger().Threshold()),
+	}
+	if h.timer != nil {
+		resp.Restore = levelString(h.restore)
+		expireAt := h.expireAt.UTC()
+		resp.ExpiresAt = &expireAt
+	}
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *LevelHandler) change(r *http.Request) error {
+	var req levelRequest
+	d := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxLevelRequestSize))
+	if err := d.Decode(&req); err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if len(req.TTL) > 0 {
+		var err error
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil {
+			return err
+		}
+		if ttl <= 0 {
+			return errors.New("ttl must be positive")
+		}
+	}
+
+	l := h.logger()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	old := l.Threshold()
+	if err := l.SetThresholdByName(req.Level); err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		FnType:          "level_change",
+		FnRemoteAddress: remoteIP(r),
+		"old_level":     levelString(old),
+		"new_level":     levelString(l.Threshold()),
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		fields["user"] = user
+	}
+
+	restore := old
+	if h.timer != nil {
+		// keep the level before the first temporary change.
+		restore = h.restore
+		h.timer.Stop()
+		h.timer = nil
+	}
+	if ttl > 0 {
+		fields["ttl"] = ttl.Seconds()
+		h.restore = restore
+		h.expireAt = time.Now().Add(ttl)
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.timer != timer {
+				return
+			}
+			h.timer = nil
+			cur := l.Threshold()
+			l.SetThreshold(restore)
+			l.writeAlways(LvWarn, "log level restored", map[string]interface{}{
+				FnType:      "level_change",
+				"old_level": levelString(cur),
+				"new_level": levelString(restore),
+			})
+		})
+		h.timer = timer
+	}
+
+	if id := RequestIDFromContext(r.Context()); len(id) > 0 {
+		fields[FnRequestID] = id
+	}
+	l.writeAlways(LvWarn, "log level changed", fields)
+	return nil
+}
@@ -0,0 +1,134 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelHandler(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetThreshold(LvError)
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	h := &LevelHandler{Logger: l}
+
+	serve := func(method, body string) (int, levelResponse) {
+		t.Helper()
+		r := httptest.NewRequest(method, "/log/level", strings.NewReader(body))
+		r.SetBasicAuth("admin", "password")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		var resp levelResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	if code, resp := serve("GET", ""); code != http.StatusOK || resp.Level != "error" || resp.ExpiresAt != nil {
+		t.Errorf("unexpected response: %d %+v", code, resp)
+	}
+
+	if code, _ := serve("PUT", `{"level":"verbose"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid level should be rejected: %d", code)
+	}
+	if code, _ := serve("PUT", `{"level":"debug","ttl":"-1s"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid ttl should be rejected: %d", code)
+	}
+	if code, _ := serve("POST", `{"level":"debug"}`); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST should not be allowed: %d", code)
+	}
+	if len(ch) != 0 {
+		t.Error("failed requests should not be audited")
+	}
+
+	code, resp := serve("PUT", `{"level":"warning","ttl":"1h"}`)
+	if code != http.StatusOK || resp.Level != "warning" || resp.Restore != "error" || resp.ExpiresAt == nil {
+		t.Errorf("unexpected response: %d %+v", code, resp)
+	}
+	// the restore level is kept over temporary changes.
+	code, resp = serve("PUT", `{"level":"info","ttl":"100ms"}`)
+	if code != http.StatusOK || resp.Level != "info" || resp.Restore != "error" {
+		t.Errorf("unexpected response: %d %+v", code, resp)
+	}
+	if l.Threshold() != LvInfo {
+		t.Error("threshold should be changed")
+	}
+
+	var records []map[string]interface{}
+	for i := 0; i < 3; i++ {
+		var record map[string]interface{}
+		select {
+		case b := <-ch:
+			if err := json.Unmarshal(b, &record); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("audit log is not output")
+		}
+		records = append(records, record)
+	}
+	expected := []struct {
+		message  string
+		oldLevel string
+		newLevel string
+	}{
+		{"log level changed", "error", "warning"},
+		{"log level changed", "warning", "info"},
+		{"log level restored", "info", "error"},
+	}
+	for i, e := range expected {
+		r := records[i]
+		if r[FnType] != "level_change" || r[FnMessage] != e.message ||
+			r["old_level"] != e.oldLevel || r["new_level"] != e.newLevel {
+			t.Errorf("unexpected audit log #%d: %v", i, r)
+		}
+	}
+	if records[0]["user"] != "admin" || records[0]["ttl"] != 3600.0 {
+		t.Errorf("unexpected audit log: %v", records[0])
+	}
+
+	if l.Threshold() != LvError {
+		t.Error("threshold should be restored")
+	}
+	if code, resp := serve("GET", ""); code != http.StatusOK || resp.Level != "error" || resp.Restore != "" {
+		t.Errorf("unexpected response: %d %+v", code, resp)
+	}
+}
+
+func TestLevelHandlerWithSinks(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetThreshold(LvError)
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	sink := make(chanWriter, 100)
+	l.SetSinks(&Sink{Output: sink, Threshold: LvCritical})
+	h := &LevelHandler{Logger: l}
+
+	r := httptest.NewRequest("PUT", "/log/level", strings.NewReader(`{"level":"critical"}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	// the audit log is written regardless of the thresholds.
+	if len(ch) != 1 {
+		t.Error("the change should be audited in the output")
+	}
+	if len(sink) != 1 {
+		t.Error("the change should be audited in the sink")
+	}
+}
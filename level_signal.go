@@ -0,0 +1,94 @@
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// ToggleThresholdOnSignal toggles the threshold of the logger between
+// the current one and level each time one of sig is received.
+// For example, with level LvDebug and syscall.SIGUSR2, the first SIGUSR2
+// enables debug logs and the second one restores the previous threshold.
+//
+// Each transition is logged with "level_change" type regardless of the
+// thresholds of the logger and its sinks.  The returned function stops
+// handling the signals.
+//
+// It panics if sig is empty.
+func (l *Logger) ToggleThresholdOnSignal(level int, sig ...os.Signal) (stop func()) {
+	toggled := false
+	saved := 0
+	return l.handleThresholdSignal(func(cur int) int {
+		if toggled && cur == level {
+			toggled = false
+			return saved
+		}
+		toggled = true
+		saved = cur
+		return level
+	}, sig)
+}
+
+// CycleThresholdOnSignal changes the threshold of the logger to the next
+// of levels each time one of sig is received.  After the last of levels,
+// the first one is used.  If the current threshold is not in levels,
+// the first one is used.
+//
+// Each transition is logged with "level_change" type regardless of the
+// thresholds of the logger and its sinks.  The returned function stops
+// handling the signals.
+//
+// It panics if levels or sig is empty.
+func (l *Logger) CycleThresholdOnSignal(levels []int, sig ...os.Signal) (stop func()) {
+	if len(levels) == 0 {
+		panic("log: no levels to cycle")
+	}
+	levels = append([]int(nil), levels...)
+	return l.handleThresholdSignal(func(cur int) int {
+		for i, level := range levels {
+			if level == cur {
+				return levels[(i+1)%len(levels)]
+			}
+		}
+		return levels[0]
+	}, sig)
+}
+
+// handleThresholdSignal changes the threshold to next(current threshold)
+// when signals are received.  sig must not be empty because
+// signal.Notify relays all incoming signals for empty sig.
+func (l *Logger) handleThresholdSignal(next func(int) int, sig []os.Signal) func() {
+	if len(sig) == 0 {
+		panic("log: no signals to change the threshold")
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sig...)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case s := <-c:
+				old := l.Threshold()
+				level := next(old)
+				l.SetThreshold(level)
+				l.writeAlways(LvWarn, "log level changed", map[string]interface{}{
+					FnType:      "level_change",
+					"old_level": levelString(old),
+					"new_level": levelString(level),
+					"signal":    s.String(),
+				})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
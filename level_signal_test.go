@@ -0,0 +1,94 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitLevelChange sends sig to the process and returns the logged transition.
+func waitLevelChange(t *testing.T, ch chanWriter, sig syscall.Signal) map[string]interface{} {
+	t.Helper()
+
+	syscall.Kill(os.Getpid(), sig)
+	select {
+	case b := <-ch:
+		var record map[string]interface{}
+		if err := json.Unmarshal(b, &record); err != nil {
+			t.Fatal(err)
+		}
+		return record
+	case <-time.After(10 * time.Second):
+		t.Fatal("level change is not logged")
+	}
+	return nil
+}
+
+// SIGWINCH is used as other signals are used in reopen_test.go.
+func TestThresholdSignal(t *testing.T) {
+	l := NewLogger()
+	l.SetThreshold(LvWarn)
+	ch := make(chanWriter, 10)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	sink := make(chanWriter, 10)
+	l.SetSinks(&Sink{Output: sink, Threshold: LvCritical})
+
+	stop := l.ToggleThresholdOnSignal(LvDebug, syscall.SIGWINCH)
+	r := waitLevelChange(t, ch, syscall.SIGWINCH)
+	if l.Threshold() != LvDebug || r["old_level"] != "warning" || r["new_level"] != "debug" {
+		t.Errorf("unexpected transition: %d %v", l.Threshold(), r)
+	}
+	if r[FnType] != "level_change" || r["signal"] != syscall.SIGWINCH.String() {
+		t.Errorf("unexpected log: %v", r)
+	}
+	r = waitLevelChange(t, ch, syscall.SIGWINCH)
+	if l.Threshold() != LvWarn || r["new_level"] != "warning" {
+		t.Errorf("unexpected transition: %d %v", l.Threshold(), r)
+	}
+	stop()
+	stop()
+
+	stop = l.CycleThresholdOnSignal([]int{LvError, LvInfo, LvDebug}, syscall.SIGWINCH)
+	defer stop()
+	for _, level := range []string{"error", "info", "debug", "error"} {
+		r = waitLevelChange(t, ch, syscall.SIGWINCH)
+		if r["new_level"] != level {
+			t.Errorf("threshold should be %s: %v", level, r)
+		}
+	}
+	if l.Threshold() != LvError {
+		t.Errorf("unexpected threshold: %d", l.Threshold())
+	}
+	// transitions are logged regardless of thresholds.
+	for i := 0; i < 6; i++ {
+		select {
+		case <-sink:
+		case <-time.After(10 * time.Second):
+			t.Fatal("level change is not logged to the sink")
+		}
+	}
+}
+
+func TestThresholdSignalEmpty(t *testing.T) {
+	cases := map[string]func(){
+		"levels": func() { NewLogger().CycleThresholdOnSignal(nil, syscall.SIGWINCH) },
+		"toggle": func() { NewLogger().ToggleThresholdOnSignal(LvDebug) },
+		"cycle":  func() { NewLogger().CycleThresholdOnSignal([]int{LvDebug}) },
+	}
+	for name, f := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: empty arguments should panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
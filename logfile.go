@@ -0,0 +1,40 @@
+package log
+
+import "os"
+
+// openLogFile opens the named file for appending logs.
+func openLogFile(filename string) (*os.File, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// correct the tail of the file if the file is not empty and
+	// does not ends with a newline.
+	size := fi.Size()
+	if size > 0 {
+		var buf [1]byte
+		_, err = f.ReadAt(buf[:], size-1)
+		if err != nil {
+			goto OUT
+		}
+		if buf[0] == byte('\n') {
+			goto OUT
+		}
+		buf[0] = byte('\n')
+		_, err = f.Write(buf[:])
+	}
+
+OUT:
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
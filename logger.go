@@ -2,6 +2,7 @@ package log
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -37,11 +38,32 @@ func init() {
 // Properties are initially set by NewLogger.  They can be customized
 // later by Logger methods.
 type Logger struct {
-	topic        atomic.Value
-	threshold    int32
-	defaults     atomic.Value
-	format       atomic.Value
-	errorHandler atomic.Value
+	topic atomic.Value
+
+	// parent and fields are set for loggers derived by With.
+	parent *Logger
+	fields map[string]interface{}
+	// invalidKey is true if fields of the logger or its ancestors
+	// contain an invalid key.
+	invalidKey bool
+
+	*core
+}
+
+// core is the set of properties shared among a logger and
+// loggers derived from it.
+type core struct {
+	threshold      int32
+	reportCaller   int32
+	stackThreshold int32
+	dedupWindow    int64
+	defaults       atomic.Value
+	format         atomic.Value
+	errorHandler   atomic.Value
+	sampler        atomic.Value
+	sinks          atomic.Value
+
+	dedup dedupState
 
 	mu     sync.Mutex
 	output io.Writer
@@ -59,7 +81,9 @@ type Logger struct {
 //	ErrorHandler: os.Exit(5) on EPIPE.
 func NewLogger() *Logger {
 	l := &Logger{
-		output: os.Stderr,
+		core: &core{
+			output: os.Stderr,
+		},
 	}
 	filename := filepath.Base(os.Args[0])
 	if runtime.GOOS == "windows" {
@@ -97,13 +121,61 @@ func normalizeTopic(n string) string {
 	return topic
 }
 
+// isValidTopic returns true if topic matches [.a-z0-9-]+ and is not
+// too long.
+func isValidTopic(topic string) bool {
+	if len(topic) == 0 || len(topic) > maxTopicLength {
+		return false
+	}
+	for i := 0; i < len(topic); i++ {
+		c := topic[i]
+		if c != '.' && c != '-' && (c < '0' || c > '9') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// With returns a new logger derived from l.
+//
+// The derived logger shares the output, threshold, formatter, defaults,
+// and error handler with l.  fields are bound to the derived logger and
+// are merged with l's defaults; they take precedence over the defaults,
+// and are overridden by fields given to logging methods.
+//
+// The topic is inherited from l unless SetTopic is called for the
+// derived logger.  If fields contain a key that is not valid in the
+// sense of IsValidKey, logging methods of the derived logger return
+// ErrInvalidKey.
+func (l *Logger) With(fields map[string]interface{}) *Logger {
+	invalidKey := l.invalidKey
+	f := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if !IsValidKey(k) {
+			invalidKey = true
+		}
+		f[k] = v
+	}
+	return &Logger{
+		parent:     l,
+		fields:     f,
+		invalidKey: invalidKey,
+		core:       l.core,
+	}
+}
+
 // Topic returns the topic for the logger.
 func (l *Logger) Topic() string {
-	return l.topic.Load().(string)
+	if t := l.topic.Load(); t != nil {
+		return t.(string)
+	}
+	return l.parent.Topic()
 }
 
 // SetTopic sets a new topic for the logger.
 // topic must not be empty.  Too long topic may be shortened automatically.
+//
+// For a logger derived by With, this changes only the topic of it.
 func (l *Logger) SetTopic(topic string) {
 	if len(topic) == 0 {
 		panic("Empty tag")
@@ -125,8 +197,17 @@ func (l *Logger) Threshold() int {
 //	        "debug info": "...",
 //	    })
 //	}
+//
+// If sinks are set by SetSinks, this returns true if the log will be
+// logged to any of the output and sinks.
 func (l *Logger) Enabled(level int) bool {
-	return level <= l.Threshold()
+	if level <= l.Threshold() {
+		return true
+	}
+	if ss := l.sinkSet(); ss != nil {
+		return level <= ss.threshold
+	}
+	return false
 }
 
 // SetThreshold sets the threshold for the logger.
@@ -137,27 +218,35 @@ func (l *Logger) SetThreshold(level int) {
 
 // SetThresholdByName sets the threshold for the logger by the level name.
 func (l *Logger) SetThresholdByName(n string) error {
-	var level int
+	level, err := levelByName(n)
+	if err != nil {
+		return err
+	}
+	l.SetThreshold(level)
+	return nil
+}
+
+// levelByName returns the level for the name.
+func levelByName(n string) (int, error) {
 	switch n {
 	case "critical", "crit":
-		level = LvCritical
+		return LvCritical, nil
 	case "error":
-		level = LvError
+		return LvError, nil
 	case "warning", "warn":
-		level = LvWarn
+		return LvWarn, nil
 	case "information", "info":
-		level = LvInfo
+		return LvInfo, nil
 	case "debug":
-		level = LvDebug
-	default:
-		return fmt.Errorf("no such level: %s", n)
+		return LvDebug, nil
 	}
-	l.SetThreshold(level)
-	return nil
+	return 0, fmt.Errorf("no such level: %s", n)
 }
 
 // SetDefaults sets default field values for the logger.
 // Setting nil effectively clear the defaults.
+//
+// Defaults are shared with loggers derived by With.
 func (l *Logger) SetDefaults(d map[string]interface{}) error {
 	for key := range d {
 		if !IsValidKey(key) {
@@ -170,8 +259,29 @@ func (l *Logger) SetDefaults(d map[string]interface{}) error {
 }
 
 // Defaults returns default field values.
+//
+// For a logger derived by With, the returned map contains fields
+// bound to the logger in addition to the parent's defaults.
 func (l *Logger) Defaults() map[string]interface{} {
-	return l.defaults.Load().(map[string]interface{})
+	if l.parent == nil {
+		return l.defaults.Load().(map[string]interface{})
+	}
+
+	pd := l.parent.Defaults()
+	if len(l.fields) == 0 {
+		return pd
+	}
+	if len(pd) == 0 {
+		return l.fields
+	}
+	d := make(map[string]interface{}, len(pd)+len(l.fields))
+	for k, v := range pd {
+		d[k] = v
+	}
+	for k, v := range l.fields {
+		d[k] = v
+	}
+	return d
 }
 
 // SetFormatter sets log formatter.
@@ -209,6 +319,73 @@ func (l *Logger) SetOutput(w io.Writer) {
 	l.mu.Unlock()
 }
 
+// Flush outputs the summary of duplicate logs suppressed by
+// SetDedupWindow, then waits until logs buffered in the output and
+// sinks are written or ctx is done.  Waiting has effect only if the
+// output implements Flusher such as AsyncWriter.
+func (l *Logger) Flush(ctx context.Context) error {
+	err := l.flushDedup()
+
+	l.mu.Lock()
+	output := l.output
+	l.mu.Unlock()
+
+	if f, ok := output.(Flusher); ok {
+		if ferr := f.Flush(ctx); ferr != nil {
+			err = ferr
+		}
+	}
+	for _, s := range l.Sinks() {
+		if ferr := s.flush(ctx); ferr != nil && err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
+// Close outputs the summary of duplicate logs, then closes the output
+// and sinks if they implement io.Closer.  os.Stdout and os.Stderr are
+// not closed.  Outputs that implement Flusher but not io.Closer are
+// flushed instead.
+//
+// Close does not call Flush of outputs that implement io.Closer
+// because their Close is expected to write buffered logs by itself.
+// Call Flush with a deadline before Close to limit the waiting time.
+//
+// The logger should not be used after Close.
+func (l *Logger) Close() error {
+	err := l.flushDedup()
+
+	l.mu.Lock()
+	output := l.output
+	l.mu.Unlock()
+
+	if cerr := closeOutput(output); err == nil {
+		err = cerr
+	}
+	for _, s := range l.Sinks() {
+		if cerr := closeOutput(s.Output); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// closeOutput closes w if w implements io.Closer, or flushes w if w
+// implements Flusher.
+func closeOutput(w io.Writer) error {
+	if w == nil || w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	if f, ok := w.(Flusher); ok {
+		return f.Flush(context.Background())
+	}
+	return nil
+}
+
 type logWriter struct {
 	buf     []byte
 	logfunc func(p []byte) (n int, err error)
@@ -270,20 +447,137 @@ func (l *Logger) Writer(severity int) io.Writer {
 // Log outputs a log message with additional fields.
 // fields can be nil.
 func (l *Logger) Log(severity int, msg string, fields map[string]interface{}) error {
-	if severity > l.Threshold() {
+	return l.logAt(time.Time{}, severity, msg, fields)
+}
+
+// logAt outputs a log message logged at t.  If t is zero, the current
+// time is used.
+func (l *Logger) logAt(t time.Time, severity int, msg string, fields map[string]interface{}) error {
+	if !l.Enabled(severity) {
+		return nil
+	}
+	if s := l.Sampler(); s != nil && !s.allow(l, severity, msg) {
 		return nil
 	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if window := l.DedupWindow(); window > 0 {
+		return l.writeDedup(window, t, severity, msg, fields)
+	}
+	return l.writeAt(t, severity, msg, fields)
+}
+
+// writeFlags modifies the behavior of writeTo.
+type writeFlags int
+
+const (
+	// writeForce writes a log regardless of thresholds.
+	writeForce writeFlags = 1 << iota
+
+	// writeInternal marks a log written by this package such as
+	// summaries and audits.  Caller information is not added to it
+	// as the caller is not the user of this package.
+	writeInternal
+)
+
+// write formats and writes a log written by this package now to the
+// output and sinks whose threshold allows severity.
+func (l *Logger) write(severity int, msg string, fields map[string]interface{}) error {
+	return l.writeTo(time.Now(), severity, msg, fields, writeInternal)
+}
+
+// writeAt formats and writes a log logged at t to the output and sinks
+// whose threshold allows severity.
+func (l *Logger) writeAt(t time.Time, severity int, msg string, fields map[string]interface{}) error {
+	return l.writeTo(t, severity, msg, fields, 0)
+}
+
+// writeAlways formats and writes a log written by this package now to
+// the output and all sinks regardless of their thresholds.  This is
+// used for audit logs.
+func (l *Logger) writeAlways(severity int, msg string, fields map[string]interface{}) error {
+	return l.writeTo(time.Now(), severity, msg, fields, writeForce|writeInternal)
+}
+
+// writeTo formats and writes a log.  Unless flags has writeForce, the
+// log is written only to the output and sinks whose threshold allows
+// severity.
+func (l *Logger) writeTo(t time.Time, severity int, msg string, fields map[string]interface{}, flags writeFlags) error {
+	if l.invalidKey {
+		return ErrInvalidKey
+	}
+
+	force := flags&writeForce != 0
+	if flags&writeInternal == 0 {
+		withStack := severity <= l.StackThreshold()
+		if withStack || l.ReportCaller() {
+			fields = addCaller(fields, withStack)
+		}
+	}
 
 	// format the message before acquiring mutex for better concurrency.
-	t := time.Now()
-	buf := pool.Get().(*[]byte)
-	defer pool.Put(buf)
+	sinks := l.Sinks()
+	if len(sinks) == 0 {
+		buf := pool.Get().(*[]byte)
+		defer pool.Put(buf)
+
+		b, err := l.Formatter().Format(*buf, l, t, severity, msg, fields)
+		if err != nil {
+			return err
+		}
+		return l.writeOutput(b)
+	}
 
-	b, err := l.Formatter().Format(*buf, l, t, severity, msg, fields)
-	if err != nil {
-		return err
+	// format once for each distinct formatter.
+	var cache []formatted
+	defer func() {
+		for _, c := range cache {
+			pool.Put(c.buf)
+		}
+	}()
+	format := func(f Formatter) ([]byte, error) {
+		for _, c := range cache {
+			if sameFormatter(c.f, f) {
+				return c.b, c.err
+			}
+		}
+		buf := pool.Get().(*[]byte)
+		b, err := f.Format(*buf, l, t, severity, msg, fields)
+		cache = append(cache, formatted{f: f, buf: buf, b: b, err: err})
+		return b, err
+	}
+
+	var errs []error
+	if force || severity <= l.Threshold() {
+		b, err := format(l.Formatter())
+		if err == nil {
+			err = l.writeOutput(b)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
+	for _, s := range sinks {
+		if !force && severity > s.threshold(l) {
+			continue
+		}
+		b, err := format(s.formatter(l))
+		if err == nil {
+			err = s.write(b)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
 
+// writeOutput writes a formatted log to the output.
+func (l *Logger) writeOutput(b []byte) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -291,7 +585,7 @@ func (l *Logger) Log(severity int, msg string, fields map[string]interface{}) er
 		return nil
 	}
 
-	_, err = l.output.Write(b)
+	_, err := l.output.Write(b)
 	if err == nil {
 		return nil
 	}
@@ -101,6 +101,75 @@ func TestLogger(t *testing.T) {
 	}
 }
 
+func TestLoggerWith(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("parent")
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(PlainFormat{"localhost"})
+	l.SetDefaults(map[string]interface{}{
+		"abc": 1,
+		"def": 2,
+	})
+
+	c := l.With(map[string]interface{}{
+		"def": 3,
+		"ghi": 4,
+	})
+	if c.Topic() != "parent" {
+		t.Error("topic should be inherited")
+	}
+	if err := c.Info("child", map[string]interface{}{"ghi": 5}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, ` parent info: "child" ghi=5`) {
+		t.Error("unexpected log: " + s)
+	}
+	for _, kv := range []string{" abc=1", " def=3"} {
+		if !strings.Contains(s, kv) {
+			t.Error("missing " + kv + ": " + s)
+		}
+	}
+
+	// the parent is not affected
+	buf.Reset()
+	l.Info("parent", nil)
+	s = buf.String()
+	if strings.Contains(s, "ghi=") || !strings.Contains(s, " def=2") {
+		t.Error("parent is affected: " + s)
+	}
+
+	// derived loggers share threshold with the parent
+	l.SetThreshold(LvError)
+	if c.Enabled(LvInfo) {
+		t.Error("threshold should be shared")
+	}
+
+	// topic can be changed independently
+	gc := c.With(nil)
+	gc.SetTopic("grandchild")
+	if l.Topic() != "parent" || c.Topic() != "parent" {
+		t.Error("parent topic should not be changed")
+	}
+	buf.Reset()
+	gc.Error("gc", nil)
+	s = buf.String()
+	if !strings.Contains(s, " grandchild error: ") || !strings.Contains(s, " ghi=4") {
+		t.Error("unexpected log: " + s)
+	}
+
+	bad := l.With(map[string]interface{}{FnMessage: "bad"})
+	if err := bad.Error("bad", nil); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+	if err := bad.With(nil).Error("bad", nil); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey for descendants, got %v", err)
+	}
+}
+
 type testFormat struct {
 }
 
@@ -163,3 +232,18 @@ func TestWriteThrough(t *testing.T) {
 		t.Error(`!bytes.Contains(data, []byte("abc\ndef\n"))`)
 	}
 }
+
+func TestIsValidTopic(t *testing.T) {
+	t.Parallel()
+
+	for _, topic := range []string{"abc", "abc.-def", "a0-9"} {
+		if !isValidTopic(topic) {
+			t.Errorf("%q should be valid", topic)
+		}
+	}
+	for _, topic := range []string{"", "Abc", "a b", "a_b", strings.Repeat("a", maxTopicLength+1)} {
+		if isValidTopic(topic) {
+			t.Errorf("%q should be invalid", topic)
+		}
+	}
+}
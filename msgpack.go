@@ -1,32 +1,62 @@
 package log
 
 import (
+	"encoding"
 	"encoding/binary"
+	"fmt"
 	"math"
+	"reflect"
 	"time"
 )
 
 // MessagePack type tags.
-// Only tags used in this code are defined.
 const (
-	mpNil      = 0xc0
-	mpFalse    = 0xc2
-	mpTrue     = 0xc3
-	mpInt16    = 0xd1
-	mpInt32    = 0xd2
-	mpInt64    = 0xd3
-	mpFixStr   = 0xa0
-	mpStr8     = 0xd9
-	mpStr16    = 0xda
-	mpStr32    = 0xdb
-	mpBin8     = 0xc4
-	mpBin16    = 0xc5
-	mpBin32    = 0xc6
-	mpFixArray = 0x90
-	mpArray16  = 0xdc
-	mpArray32  = 0xdd
-	mpFixMap   = 0x80
-	mpMap16    = 0xde
+	mpFixMap      = 0x80
+	mpFixMapMax   = 0x8f
+	mpFixArray    = 0x90
+	mpFixArrayMax = 0x9f
+	mpFixStr      = 0xa0
+	mpFixStrMax   = 0xbf
+	mpNil         = 0xc0
+	mpFalse       = 0xc2
+	mpTrue        = 0xc3
+	mpBin8        = 0xc4
+	mpBin16       = 0xc5
+	mpBin32       = 0xc6
+	mpExt8        = 0xc7
+	mpExt16       = 0xc8
+	mpExt32       = 0xc9
+	mpFloat32     = 0xca
+	mpFloat64     = 0xcb
+	mpUint8       = 0xcc
+	mpUint16      = 0xcd
+	mpUint32      = 0xce
+	mpUint64      = 0xcf
+	mpInt8        = 0xd0
+	mpInt16       = 0xd1
+	mpInt32       = 0xd2
+	mpInt64       = 0xd3
+	mpFixExt1     = 0xd4
+	mpFixExt2     = 0xd5
+	mpFixExt4     = 0xd6
+	mpFixExt8     = 0xd7
+	mpFixExt16    = 0xd8
+	mpStr8        = 0xd9
+	mpStr16       = 0xda
+	mpStr32       = 0xdb
+	mpArray16     = 0xdc
+	mpArray32     = 0xdd
+	mpMap16       = 0xde
+	mpMap32       = 0xdf
+	mpNegFixInt   = 0xe0
+)
+
+// MessagePack extension types.
+const (
+	// mpExtEventTime is the EventTime extension of Fluentd Forward protocol.
+	mpExtEventTime = 0
+	// mpExtTimestamp is the timestamp extension type.
+	mpExtTimestamp = -1
 )
 
 func appendMsgpackInt64(b []byte, n int64) []byte {
@@ -48,6 +78,13 @@ func appendMsgpackInt64(b []byte, n int64) []byte {
 	}
 }
 
+func appendMsgpackEventTime(b []byte, t time.Time) []byte {
+	b = append(b, mpFixExt8, mpExtEventTime, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-8:], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[len(b)-4:], uint32(t.Nanosecond()))
+	return b
+}
+
 func appendMsgpackString(b []byte, s string) ([]byte, error) {
 	switch {
 	case len(s) >= maxLogSize:
@@ -84,7 +121,62 @@ func appendMsgpackArray(b []byte, length int) ([]byte, error) {
 	}
 }
 
+func appendMsgpackMap(b []byte, length int) ([]byte, error) {
+	switch {
+	case length <= 15:
+		return append(b, byte(mpFixMap+length)), nil
+	case length <= math.MaxUint16:
+		b = append(b, byte(mpMap16), 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(length))
+		return b, nil
+	case length <= math.MaxUint32:
+		b = append(b, byte(mpMap32), 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(length))
+		return b, nil
+	default:
+		return nil, ErrTooLarge
+	}
+}
+
+func appendMsgpackUint64(b []byte, n uint64) []byte {
+	if n <= math.MaxInt64 {
+		return appendMsgpackInt64(b, int64(n))
+	}
+	b = append(b, mpUint64, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(b[len(b)-8:], n)
+	return b
+}
+
+// appendMsgpackTimestamp appends t as the timestamp extension type
+// in the smallest of 32, 64, and 96 bit formats.
+func appendMsgpackTimestamp(b []byte, t time.Time) []byte {
+	sec := t.Unix()
+	nsec := uint64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		b = append(b, mpFixExt4, 0xff, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(sec))
+	case sec>>34 == 0:
+		b = append(b, mpFixExt8, 0xff, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], nsec<<34|uint64(sec))
+	default:
+		b = append(b, mpExt8, 12, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-12:], uint32(nsec))
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(sec))
+	}
+	return b
+}
+
 func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
+	return appendMsgpackValue(b, v, false)
+}
+
+// appendMsgpackValue appends v in MessagePack format.
+// If tsExt is true, time.Time is encoded as the timestamp extension type,
+// otherwise as an integer in microseconds.
+func appendMsgpackValue(b []byte, v interface{}, tsExt bool) ([]byte, error) {
+	var err error
+
 	switch t := v.(type) {
 	case nil:
 		return append(b, mpNil), nil
@@ -95,9 +187,36 @@ func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
 		return append(b, mpFalse), nil
 	case int:
 		return appendMsgpackInt64(b, int64(t)), nil
+	case int8:
+		return appendMsgpackInt64(b, int64(t)), nil
+	case int16:
+		return appendMsgpackInt64(b, int64(t)), nil
+	case int32:
+		return appendMsgpackInt64(b, int64(t)), nil
 	case int64:
 		return appendMsgpackInt64(b, t), nil
+	case uint:
+		return appendMsgpackUint64(b, uint64(t)), nil
+	case uint8:
+		return appendMsgpackUint64(b, uint64(t)), nil
+	case uint16:
+		return appendMsgpackUint64(b, uint64(t)), nil
+	case uint32:
+		return appendMsgpackUint64(b, uint64(t)), nil
+	case uint64:
+		return appendMsgpackUint64(b, t), nil
+	case float32:
+		b = append(b, mpFloat32, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], math.Float32bits(t))
+		return b, nil
+	case float64:
+		b = append(b, mpFloat64, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], math.Float64bits(t))
+		return b, nil
 	case time.Time:
+		if tsExt {
+			return appendMsgpackTimestamp(b, t), nil
+		}
 		return appendMsgpackInt64(b, t.UnixNano()/1000), nil
 	case string:
 		return appendMsgpackString(b, t)
@@ -117,7 +236,7 @@ func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
 		}
 		return append(b, t...), nil
 	case []int:
-		b, err := appendMsgpackArray(b, len(t))
+		b, err = appendMsgpackArray(b, len(t))
 		if err != nil {
 			return nil, err
 		}
@@ -126,7 +245,7 @@ func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
 		}
 		return b, nil
 	case []int64:
-		b, err := appendMsgpackArray(b, len(t))
+		b, err = appendMsgpackArray(b, len(t))
 		if err != nil {
 			return nil, err
 		}
@@ -135,7 +254,7 @@ func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
 		}
 		return b, nil
 	case []string:
-		b, err := appendMsgpackArray(b, len(t))
+		b, err = appendMsgpackArray(b, len(t))
 		if err != nil {
 			return nil, err
 		}
@@ -146,9 +265,58 @@ func appendMsgpack(b []byte, v interface{}) ([]byte, error) {
 			}
 		}
 		return b, nil
-	default:
-		return nil, ErrInvalidData
+	case encoding.TextMarshaler:
+		s, err := t.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgpackString(b, string(s))
+	case error:
+		return appendMsgpackString(b, t.Error())
+	case fmt.Stringer:
+		return appendMsgpackString(b, t.String())
+	}
+
+	value := reflect.ValueOf(v)
+	typ := value.Type()
+	kind := typ.Kind()
+
+	// string-keyed maps
+	if kind == reflect.Map && typ.Key().Kind() == reflect.String {
+		b, err = appendMsgpackMap(b, value.Len())
+		if err != nil {
+			return nil, err
+		}
+		for iter := value.MapRange(); iter.Next(); {
+			b, err = appendMsgpackString(b, iter.Key().String())
+			if err != nil {
+				return nil, err
+			}
+			b, err = appendMsgpackValue(b, iter.Value().Interface(), tsExt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+
+	// slices and arrays
+	if kind == reflect.Slice || kind == reflect.Array {
+		b, err = appendMsgpackArray(b, value.Len())
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < value.Len(); i++ {
+			b, err = appendMsgpackValue(b, value.Index(i).Interface(), tsExt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
 	}
+
+	// other types are just formatted as a string with "%#v".
+	return appendMsgpackString(b, fmt.Sprintf("%#v", v))
 }
 
 // MsgPack implements Formatter for msgpack format.
@@ -159,6 +327,17 @@ type MsgPack struct {
 	// If not empty, the string is used instead of the hostname.
 	// Utsname must match this regexp: ^[a-z][a-z0-9-]*$
 	Utsname string
+
+	// EventTime encodes the time of the event, the second element of
+	// the array, as EventTime extension type of Fluentd Forward protocol
+	// for sub-second precision.  If false, the time is encoded as an
+	// integer in seconds.
+	EventTime bool
+
+	// TimestampExt encodes logged_at and time.Time values in fields
+	// as the timestamp extension type.  If false, they are encoded as
+	// integers in microseconds.
+	TimestampExt bool
 }
 
 // String returns "msgpack".
@@ -174,9 +353,13 @@ func (m MsgPack) Format(b []byte, l *Logger, t time.Time, severity int, msg stri
 	if err != nil {
 		return nil, err
 	}
-	b, err = appendMsgpack(b, t.Unix())
-	if err != nil {
-		return nil, err
+	if m.EventTime {
+		b = appendMsgpackEventTime(b, t)
+	} else {
+		b, err = appendMsgpack(b, t.Unix())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// the log record consists of these objects:
@@ -202,11 +385,9 @@ func (m MsgPack) Format(b []byte, l *Logger, t time.Time, severity int, msg stri
 		return nil, ErrTooLarge
 	}
 
-	if nFields <= 15 {
-		b = append(b, byte(mpFixMap+nFields))
-	} else {
-		b = append(b, byte(mpMap16), 0, 0)
-		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(nFields))
+	b, err = appendMsgpackMap(b, int(nFields))
+	if err != nil {
+		return nil, err
 	}
 
 	// logged_at
@@ -214,7 +395,7 @@ func (m MsgPack) Format(b []byte, l *Logger, t time.Time, severity int, msg stri
 	if err != nil {
 		return nil, err
 	}
-	b, err = appendMsgpack(b, t.UnixNano()/1000)
+	b, err = appendMsgpackValue(b, t, m.TimestampExt)
 	if err != nil {
 		return nil, err
 	}
@@ -269,7 +450,7 @@ func (m MsgPack) Format(b []byte, l *Logger, t time.Time, severity int, msg stri
 			return nil, err
 		}
 
-		b, err = appendMsgpack(b, v)
+		b, err = appendMsgpackValue(b, v, m.TimestampExt)
 		if err != nil {
 			return nil, err
 		}
@@ -291,7 +472,7 @@ func (m MsgPack) Format(b []byte, l *Logger, t time.Time, severity int, msg stri
 			return nil, err
 		}
 
-		b, err = appendMsgpack(b, v)
+		b, err = appendMsgpackValue(b, v, m.TimestampExt)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,232 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// maxMsgpackDecodeSize limits the length of strings, arrays and maps
+// to prevent malformed data from consuming large memory.
+const maxMsgpackDecodeSize = maxLogSize
+
+var errMsgpackFormat = errors.New("invalid msgpack data")
+
+type msgpackReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// msgpackDecoder decodes MessagePack objects into Go values.
+//
+// Integers are decoded as int64 unless they overflow int64.
+// Floats are decoded as float64.  Maps are decoded as
+// map[string]interface{}, arrays as []interface{}, and binaries as []byte.
+// Timestamp and Fluentd EventTime extensions are decoded as time.Time.
+type msgpackDecoder struct {
+	r msgpackReader
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n > maxMsgpackDecodeSize {
+		return nil, ErrTooLarge
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.r, b)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+func (d *msgpackDecoder) readUint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// decode decodes the next object.
+// io.EOF is returned only when no data is available.
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := d.decodeBody(c)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+func (d *msgpackDecoder) decodeBody(c byte) (interface{}, error) {
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= mpNegFixInt:
+		return int64(int8(c)), nil
+	case c <= mpFixMapMax:
+		return d.decodeMap(int(c - mpFixMap))
+	case c <= mpFixArrayMax:
+		return d.decodeArray(int(c - mpFixArray))
+	case c <= mpFixStrMax:
+		b, err := d.read(int(c - mpFixStr))
+		return string(b), err
+	}
+
+	switch c {
+	case mpNil:
+		return nil, nil
+	case mpFalse:
+		return false, nil
+	case mpTrue:
+		return true, nil
+	case mpBin8, mpBin16, mpBin32:
+		n, err := d.readUint(1 << (c - mpBin8))
+		if err != nil {
+			return nil, err
+		}
+		return d.read(int(n))
+	case mpExt8, mpExt16, mpExt32:
+		n, err := d.readUint(1 << (c - mpExt8))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case mpFloat32:
+		n, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case mpFloat64:
+		n, err := d.readUint(8)
+		return math.Float64frombits(n), err
+	case mpUint8, mpUint16, mpUint32, mpUint64:
+		n, err := d.readUint(1 << (c - mpUint8))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case mpInt8, mpInt16, mpInt32, mpInt64:
+		size := 1 << (c - mpInt8)
+		n, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 1:
+			return int64(int8(n)), nil
+		case 2:
+			return int64(int16(n)), nil
+		case 4:
+			return int64(int32(n)), nil
+		default:
+			return int64(n), nil
+		}
+	case mpFixExt1, mpFixExt2, mpFixExt4, mpFixExt8, mpFixExt16:
+		return d.decodeExt(1 << (c - mpFixExt1))
+	case mpStr8, mpStr16, mpStr32:
+		n, err := d.readUint(1 << (c - mpStr8))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(int(n))
+		return string(b), err
+	case mpArray16, mpArray32:
+		n, err := d.readUint(2 << (c - mpArray16))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case mpMap16, mpMap32:
+		n, err := d.readUint(2 << (c - mpMap16))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, errMsgpackFormat
+}
+
+func (d *msgpackDecoder) decodeArray(n int) (interface{}, error) {
+	if n > maxMsgpackDecodeSize {
+		return nil, ErrTooLarge
+	}
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int) (interface{}, error) {
+	if n > maxMsgpackDecodeSize {
+		return nil, ErrTooLarge
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		switch k := k.(type) {
+		case string:
+			m[k] = v
+		default:
+			m[fmt.Sprint(k)] = v
+		}
+	}
+	return m, nil
+}
+
+func (d *msgpackDecoder) decodeExt(n int) (interface{}, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case int8(typ) == mpExtEventTime && n == 8:
+		sec := binary.BigEndian.Uint32(b)
+		nsec := binary.BigEndian.Uint32(b[4:])
+		return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+	case int8(typ) == mpExtTimestamp && n == 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0).UTC(), nil
+	case int8(typ) == mpExtTimestamp && n == 8:
+		v := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(v&0x3ffffffff), int64(v>>34)).UTC(), nil
+	case int8(typ) == mpExtTimestamp && n == 12:
+		nsec := binary.BigEndian.Uint32(b)
+		sec := int64(binary.BigEndian.Uint64(b[4:]))
+		return time.Unix(sec, int64(nsec)).UTC(), nil
+	}
+	return b, nil
+}
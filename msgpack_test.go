@@ -1,6 +1,10 @@
 package log
 
 import (
+	"bytes"
+	"errors"
+	"math"
+	"net"
 	"strconv"
 	"testing"
 	"time"
@@ -137,8 +141,64 @@ func TestAppendMsgpack(t *testing.T) {
 		}
 	}
 
-	if _, err := appendMsgpack(b, []interface{}{"a", 10}); err != ErrInvalidData {
-		t.Error("[]interface{} must be an invalid data")
+	testCases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{int8(-1), "\xd1\xff\xff"},
+		{uint8(200), "\xd1\x00\xc8"},
+		{uint64(math.MaxUint64), "\xcf\xff\xff\xff\xff\xff\xff\xff\xff"},
+		{float32(1.5), "\xca\x3f\xc0\x00\x00"},
+		{1.5, "\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00"},
+		{[]interface{}{"a", 10}, "\x92\xa1a\x0a"},
+		{[2]bool{true, false}, "\x92\xc3\xc2"},
+		{map[string]int{"a": 1}, "\x81\xa1a\x01"},
+		{map[string]interface{}{"a": []string{"b"}}, "\x81\xa1a\x91\xa1b"},
+		{errors.New("err"), "\xa3err"},
+		{net.IPv4(127, 0, 0, 1), "\xa9127.0.0.1"},
+		{time.Second, "\xa21s"},
+		{struct{}{}, "\xabstruct {}{}"},
+	}
+	for _, tc := range testCases {
+		b2, err := appendMsgpack(b, tc.value)
+		if err != nil {
+			t.Errorf("%#v: %v", tc.value, err)
+			continue
+		}
+		if string(b2) != tc.expected {
+			t.Errorf("%#v: %q != %q", tc.value, b2, tc.expected)
+		}
+	}
+}
+
+func TestAppendMsgpackTimestamp(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		ts       time.Time
+		expected string
+	}{
+		{time.Unix(1, 0), "\xd6\xff\x00\x00\x00\x01"},
+		{time.Unix(1, 1), "\xd7\xff\x00\x00\x00\x04\x00\x00\x00\x01"},
+		{time.Unix(-1, 0), "\xc7\x0c\xff\x00\x00\x00\x00\xff\xff\xff\xff\xff\xff\xff\xff"},
+	}
+	for _, tc := range testCases {
+		b, err := appendMsgpackValue(nil, tc.ts, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.expected {
+			t.Errorf("%v: %q != %q", tc.ts, b, tc.expected)
+		}
+
+		d := msgpackDecoder{bytes.NewReader(b)}
+		v, err := d.decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts, ok := v.(time.Time); !ok || !ts.Equal(tc.ts) {
+			t.Errorf("%v: decoded as %v", tc.ts, v)
+		}
 	}
 }
 
@@ -149,7 +209,7 @@ func TestMsgpackFmt1(t *testing.T) {
 	l.SetTopic("tag1")
 
 	ts := time.Date(1970, time.January, 1, 0, 0, 10, 32000, time.UTC)
-	f := MsgPack{"localhost"}
+	f := MsgPack{Utsname: "localhost"}
 	b := make([]byte, 0, 4096)
 
 	if buf, err := f.Format(b, l, ts, LvDebug, "test message", nil); err != nil {
@@ -170,7 +230,7 @@ func TestMsgpackFmt2(t *testing.T) {
 	l.SetDefaults(map[string]interface{}{FnSecret: true})
 
 	ts := time.Date(1970, time.January, 1, 0, 0, 10, 32000, time.UTC)
-	f := MsgPack{"localhost"}
+	f := MsgPack{Utsname: "localhost"}
 	b := make([]byte, 0, 4096)
 
 	if buf, err := f.Format(b, l, ts, LvDebug, "test message", nil); err != nil {
@@ -195,3 +255,39 @@ func TestMsgpackFmt2(t *testing.T) {
 		}
 	}
 }
+
+func TestMsgpackTimestampExt(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("tag")
+
+	ts := time.Date(2020, time.January, 1, 0, 0, 0, 1000, time.UTC)
+	f := MsgPack{Utsname: "localhost", TimestampExt: true}
+	buf, err := f.Format(nil, l, ts, LvInfo, "test message", map[string]interface{}{
+		"at":    ts,
+		"ratio": 0.5,
+		"tags":  []interface{}{"a", uint(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := msgpackDecoder{bytes.NewReader(buf)}
+	v, err := d.decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := v.([]interface{})[2].(map[string]interface{})
+	for _, k := range []string{FnLoggedAt, "at"} {
+		if at, ok := record[k].(time.Time); !ok || !at.Equal(ts) {
+			t.Errorf("%s should be a timestamp: %#v", k, record[k])
+		}
+	}
+	if record["ratio"] != 0.5 {
+		t.Errorf("unexpected ratio: %#v", record["ratio"])
+	}
+	if tags := record["tags"].([]interface{}); len(tags) != 2 || tags[0] != "a" || tags[1] != int64(1) {
+		t.Errorf("unexpected tags: %#v", tags)
+	}
+}
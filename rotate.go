@@ -0,0 +1,303 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rotatedTimeFormat is the time format used for names of rotated files.
+	rotatedTimeFormat = "20060102T150405.000000"
+
+	compressedSuffix = ".gz"
+)
+
+// RotatingFileWriter is an io.WriteCloser that writes logs into a file
+// and rotates the file by its size and/or by time.
+//
+// Rotated files are renamed to Filename + "." + the rotation time in
+// UTC such as "app.log.20230201T123456.000000".  If Compress is true,
+// rotated files are gzip-compressed in the background and ".gz" is
+// appended to their names.
+//
+// The file is opened when the first log is written.  If the file does
+// not end with a newline, a newline is added like NewFileReopener.
+//
+// The file is closed before it is renamed on rotation, so this works
+// on Windows too unless other processes keep the file open.
+//
+// Fields must not be changed after the first Write.
+type RotatingFileWriter struct {
+	// Filename is the name of the log file.  This is required.
+	Filename string
+
+	// MaxSize is the maximum size of the log file in bytes.
+	// The file is rotated before it grows larger than MaxSize.
+	// Zero disables size-based rotation.
+	MaxSize int64
+
+	// Interval is the maximum duration since the file is opened.
+	// Zero disables time-based rotation.
+	Interval time.Duration
+
+	// MaxBackups is the number of rotated files to keep.
+	// Zero keeps all rotated files.
+	MaxBackups int
+
+	// Compress enables gzip compression of rotated files.
+	Compress bool
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	jobs    chan string
+	wg      sync.WaitGroup
+	errLock sync.Mutex
+	bgErr   error
+}
+
+// Write writes p into the file.  The file may be rotated before writing.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.needRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	return w.rotate()
+}
+
+// Close closes the file and waits for the completion of background
+// compression and deletion of old files.  It returns the first error
+// happened in the background, if any.
+//
+// Writing after Close reopens the file.
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	if w.jobs != nil {
+		close(w.jobs)
+		w.jobs = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+
+	w.errLock.Lock()
+	defer w.errLock.Unlock()
+	if err == nil {
+		err = w.bgErr
+	}
+	w.bgErr = nil
+	return err
+}
+
+func (w *RotatingFileWriter) open() error {
+	if len(w.Filename) == 0 {
+		return errors.New("RotatingFileWriter: no filename")
+	}
+
+	f, err := openLogFile(w.Filename)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = fi.Size()
+	w.openedAt = time.Now()
+	return nil
+}
+
+func (w *RotatingFileWriter) needRotate(n int) bool {
+	if w.MaxSize > 0 && w.size > 0 && w.size+int64(n) > w.MaxSize {
+		return true
+	}
+	if w.Interval > 0 && time.Since(w.openedAt) >= w.Interval {
+		return true
+	}
+	return false
+}
+
+func (w *RotatingFileWriter) rotate() error {
+	// forget the file even if Close fails so that it is reopened.
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+
+	rotated := w.rotatedName(time.Now())
+	if err := os.Rename(w.Filename, rotated); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	if !w.Compress && w.MaxBackups == 0 {
+		return nil
+	}
+	if w.jobs == nil {
+		w.jobs = make(chan string, 16)
+		w.wg.Add(1)
+		go w.worker(w.jobs)
+	}
+	w.jobs <- rotated
+	return nil
+}
+
+// rotatedName returns a name for a rotated file that does not conflict
+// with existing files.
+func (w *RotatingFileWriter) rotatedName(t time.Time) string {
+	for {
+		name := w.Filename + "." + t.UTC().Format(rotatedTimeFormat)
+		if !fileExists(name) && !fileExists(name+compressedSuffix) {
+			return name
+		}
+		t = t.Add(time.Microsecond)
+	}
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// worker compresses rotated files and removes old ones.
+// Jobs are processed sequentially to avoid conflicts.
+func (w *RotatingFileWriter) worker(jobs <-chan string) {
+	defer w.wg.Done()
+
+	for rotated := range jobs {
+		if w.Compress {
+			w.setError(compressFile(rotated))
+		}
+		if w.MaxBackups > 0 {
+			w.setError(w.removeOldFiles())
+		}
+	}
+}
+
+func (w *RotatingFileWriter) setError(err error) {
+	if err == nil {
+		return
+	}
+	w.errLock.Lock()
+	if w.bgErr == nil {
+		w.bgErr = err
+	}
+	w.errLock.Unlock()
+}
+
+func (w *RotatingFileWriter) removeOldFiles() error {
+	matches, err := filepath.Glob(w.Filename + ".*")
+	if err != nil {
+		return err
+	}
+
+	prefix := w.Filename + "."
+	var rotated []string
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, prefix), compressedSuffix)
+		if _, err := time.Parse(rotatedTimeFormat, ts); err != nil {
+			continue
+		}
+		rotated = append(rotated, m)
+	}
+	if len(rotated) <= w.MaxBackups {
+		return nil
+	}
+
+	// names are sorted in chronological order.
+	sort.Strings(rotated)
+	for _, m := range rotated[:len(rotated)-w.MaxBackups] {
+		if err := os.Remove(m); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func compressFile(filename string) (err error) {
+	src, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		// already removed as an old file.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpname := filename + compressedSuffix + ".tmp"
+	dst, err := os.OpenFile(tmpname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmpname)
+		}
+	}()
+
+	gw := gzip.NewWriter(dst)
+	if _, err = io.Copy(gw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpname, filename+compressedSuffix); err != nil {
+		return err
+	}
+	// open files cannot be removed on Windows.
+	src.Close()
+	return os.Remove(filename)
+}
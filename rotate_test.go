@@ -0,0 +1,179 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileWriterSize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "test.log")
+	if err := os.WriteFile(fname, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &RotatingFileWriter{
+		Filename:   fname,
+		MaxSize:    100,
+		MaxBackups: 2,
+	}
+	lg := NewLogger()
+	lg.SetOutput(w)
+	lg.SetFormatter(testFormat{})
+
+	line := strings.Repeat("x", 39) + "\n"
+	for i := 0; i < 10; i++ {
+		if err := lg.Critical(line, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := filepath.Glob(fname + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("number of rotated files should be 2 but %d", len(rotated))
+	}
+	for _, r := range rotated {
+		data, err := os.ReadFile(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 80 {
+			t.Errorf("%s: size should be 80 but %d", r, len(data))
+		}
+	}
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != line+line {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+func TestRotatingFileWriterCorrection(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "test.log")
+	if err := os.WriteFile(fname, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &RotatingFileWriter{Filename: fname}
+	if _, err := w.Write([]byte("def\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "abc\ndef\n" {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+func TestRotatingFileWriterInterval(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "test.log")
+
+	w := &RotatingFileWriter{
+		Filename: fname,
+		Interval: 100 * time.Millisecond,
+		Compress: true,
+	}
+	if _, err := w.Write([]byte("hoge\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err := w.Write([]byte("fuga\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := filepath.Glob(fname + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("number of rotated files should be 1 but %d", len(rotated))
+	}
+	if !strings.HasSuffix(rotated[0], ".gz") {
+		t.Fatal("rotated file should be compressed: " + rotated[0])
+	}
+
+	f, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte("hoge\n")) {
+		t.Errorf("unexpected content: %q", data)
+	}
+
+	data, err = os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte("fuga\n")) {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+func TestRotatingFileWriterCloseError(t *testing.T) {
+	t.Parallel()
+
+	fname := filepath.Join(t.TempDir(), "test.log")
+	w := &RotatingFileWriter{Filename: fname}
+	defer w.Close()
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// make Close in rotation fail.
+	w.file.Close()
+	if err := w.Rotate(); err == nil {
+		t.Error("Rotate should fail")
+	}
+
+	if _, err := w.Write([]byte("second\n")); err != nil {
+		t.Fatal("writer should recover:", err)
+	}
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
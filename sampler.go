@@ -0,0 +1,198 @@
+package log
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// samplerSize is the number of counters in a Sampler.
+	// Keys are hashed into the counters, so different keys may
+	// share a counter occasionally.
+	samplerSize = 4096
+
+	defaultSamplerInterval = time.Second
+)
+
+// Sampler suppresses logs to reduce the volume.
+//
+// Logs are sampled by the pair of the severity and the message.
+// For each pair, the first First logs in an Interval are output,
+// and thereafter every Thereafter-th log is output.
+//
+// In addition, if Rate is positive, logs are limited per topic by
+// a token bucket that is refilled Rate tokens per second up to Burst.
+//
+// If logs are suppressed in an Interval, a summary log with FnSuppressed
+// field is output at the end of the Interval.  The severity of the
+// summary is the highest severity among the suppressed logs, and the
+// summary is filtered by thresholds like other logs.
+//
+// Set a Sampler to a logger by Logger.SetSampler.  Fields must not be
+// changed after that.
+type Sampler struct {
+	// Interval is the period to reset counters.
+	// If zero, one second is used.
+	Interval time.Duration
+
+	// First is the number of logs output for each pair in an interval.
+	// If zero, sampling by the pair is disabled.
+	First int
+
+	// Thereafter makes every Thereafter-th log be output after First.
+	// If zero, all logs after First are suppressed.
+	Thereafter int
+
+	// Rate is the number of logs per second allowed for each topic.
+	// If zero, rate limiting is disabled.
+	Rate float64
+
+	// Burst is the maximum number of logs output at once for each topic.
+	// If zero, Rate rounded up is used.
+	Burst int
+
+	counters   [samplerSize]samplerCounter
+	suppressed uint64
+	scheduled  int32
+
+	// severity is the highest severity of suppressed logs plus one.
+	// Zero means no logs are suppressed.
+	severity int32
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type samplerCounter struct {
+	resetAt int64
+	count   uint64
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// SetSampler sets s to sample logs.  If s is nil, sampling is disabled.
+//
+// The sampler is shared with loggers derived by With.
+func (l *Logger) SetSampler(s *Sampler) {
+	l.sampler.Store(&s)
+}
+
+// Sampler returns the current sampler, or nil.
+func (l *Logger) Sampler() *Sampler {
+	s, _ := l.sampler.Load().(**Sampler)
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func (s *Sampler) interval() time.Duration {
+	if s.Interval == 0 {
+		return defaultSamplerInterval
+	}
+	return s.Interval
+}
+
+// hashKey returns FNV-1a hash of severity and msg.
+func hashKey(severity int, msg string) uint32 {
+	const prime32 = 16777619
+	h := uint32(2166136261)
+	h = (h ^ uint32(severity)) * prime32
+	for i := 0; i < len(msg); i++ {
+		h = (h ^ uint32(msg[i])) * prime32
+	}
+	return h
+}
+
+// allow returns true if the log should be output.
+func (s *Sampler) allow(l *Logger, severity int, msg string) bool {
+	now := time.Now()
+	if s.First > 0 && !s.sample(now, severity, msg) {
+		s.suppress(l, severity)
+		return false
+	}
+	if s.Rate > 0 && !s.take(now, l.Topic()) {
+		s.suppress(l, severity)
+		return false
+	}
+	return true
+}
+
+func (s *Sampler) sample(now time.Time, severity int, msg string) bool {
+	c := &s.counters[hashKey(severity, msg)%samplerSize]
+
+	n := now.UnixNano()
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if n > resetAt {
+		if atomic.CompareAndSwapInt64(&c.resetAt, resetAt, n+int64(s.interval())) {
+			atomic.StoreUint64(&c.count, 1)
+			return true
+		}
+	}
+
+	count := atomic.AddUint64(&c.count, 1)
+	first := uint64(s.First)
+	if count <= first {
+		return true
+	}
+	return s.Thereafter > 0 && (count-first)%uint64(s.Thereafter) == 0
+}
+
+func (s *Sampler) take(now time.Time, topic string) bool {
+	burst := float64(s.Burst)
+	if burst == 0 {
+		burst = math.Ceil(s.Rate)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buckets == nil {
+		s.buckets = make(map[string]*tokenBucket)
+	}
+	b, ok := s.buckets[topic]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		s.buckets[topic] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*s.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// suppress counts a suppressed log and schedules a summary log.
+func (s *Sampler) suppress(l *Logger, severity int) {
+	atomic.AddUint64(&s.suppressed, 1)
+	for {
+		old := atomic.LoadInt32(&s.severity)
+		if old != 0 && int(old) <= severity+1 {
+			break
+		}
+		if atomic.CompareAndSwapInt32(&s.severity, old, int32(severity+1)) {
+			break
+		}
+	}
+
+	if atomic.CompareAndSwapInt32(&s.scheduled, 0, 1) {
+		time.AfterFunc(s.interval(), func() {
+			atomic.StoreInt32(&s.scheduled, 0)
+			n := atomic.SwapUint64(&s.suppressed, 0)
+			severity := int(atomic.SwapInt32(&s.severity, 0)) - 1
+			if n == 0 || severity < 0 {
+				return
+			}
+			l.write(severity, "logs are suppressed by sampling", map[string]interface{}{
+				FnSuppressed: n,
+			})
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	l.SetSampler(&Sampler{
+		Interval:   time.Hour,
+		First:      2,
+		Thereafter: 3,
+	})
+
+	for i := 0; i < 10; i++ {
+		if err := l.Warn("hot", map[string]interface{}{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Warn("cold", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var output []interface{}
+	for len(ch) > 0 {
+		var record map[string]interface{}
+		if err := json.Unmarshal(<-ch, &record); err != nil {
+			t.Fatal(err)
+		}
+		output = append(output, record["i"])
+	}
+	expected := []interface{}{0.0, 1.0, 4.0, 7.0, nil}
+	if len(output) != len(expected) {
+		t.Fatalf("unexpected logs: %v", output)
+	}
+	for i := range expected {
+		if output[i] != expected[i] {
+			t.Errorf("unexpected logs: %v", output)
+		}
+	}
+}
+
+func TestSamplerRate(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	l.SetSampler(&Sampler{
+		Interval: 100 * time.Millisecond,
+		Rate:     0.1,
+		Burst:    2,
+	})
+
+	for i := 0; i < 5; i++ {
+		if err := l.Info("hello", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ch) != 2 {
+		t.Errorf("2 logs should be output but %d", len(ch))
+	}
+	<-ch
+	<-ch
+
+	// a summary log is output after the interval.
+	var record map[string]interface{}
+	select {
+	case b := <-ch:
+		if err := json.Unmarshal(b, &record); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("summary log is not output")
+	}
+	if record[FnSeverity] != "info" || record[FnSuppressed] != 3.0 {
+		t.Errorf("unexpected summary: %v", record)
+	}
+
+	l.SetSampler(nil)
+	if l.Sampler() != nil {
+		t.Error("sampler should be removed")
+	}
+	if err := l.Info("hello", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 1 {
+		t.Error("log should be output without sampler")
+	}
+}
+
+func TestSamplerSummarySeverity(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetThreshold(LvError)
+	ch := make(chanWriter, 100)
+	l.SetOutput(ch)
+	l.SetFormatter(JSONFormat{})
+	l.SetSampler(&Sampler{
+		Interval: 100 * time.Millisecond,
+		First:    1,
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := l.Error("hot", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Critical("hot", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ch) != 2 {
+		t.Fatalf("2 logs should be output but %d", len(ch))
+	}
+	<-ch
+	<-ch
+
+	// the summary has the highest severity of suppressed logs.
+	var record map[string]interface{}
+	select {
+	case b := <-ch:
+		if err := json.Unmarshal(b, &record); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("summary log is not output")
+	}
+	if record[FnSeverity] != "critical" || record[FnSuppressed] != 4.0 {
+		t.Errorf("unexpected summary: %v", record)
+	}
+}
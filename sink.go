@@ -0,0 +1,145 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Sink is an additional output of a logger with its own formatter,
+// threshold, and error handler.
+//
+// Sinks are set to a logger by Logger.SetSinks.  Fields must not be
+// changed after that.
+type Sink struct {
+	// Output is the destination of logs.
+	Output io.Writer
+
+	// Formatter formats logs for the sink.
+	// If nil, the formatter of the logger is used.
+	Formatter Formatter
+
+	// Threshold is the threshold for the sink.
+	// If zero, the threshold of the logger is used.
+	Threshold int
+
+	// ErrorHandler is called if Output.Write returns an error.
+	// If nil, the error is returned as it is.
+	ErrorHandler func(error) error
+
+	mu sync.Mutex
+}
+
+// sinkSet is the set of sinks stored in a logger.
+type sinkSet struct {
+	sinks []*Sink
+	// threshold is the maximum of thresholds of sinks.
+	threshold int
+}
+
+// formatted is a log formatted by a formatter.
+type formatted struct {
+	f   Formatter
+	buf *[]byte
+	b   []byte
+	err error
+}
+
+// SetSinks sets additional outputs of the logger.  Logs are written to
+// the output of the logger as well as sinks.  Setting no sinks removes
+// the sinks.
+//
+// A log is formatted once for each distinct formatter.  The log is
+// written to sinks one by one.  An error in a sink does not prevent
+// the log from being written to other sinks; errors are joined and
+// returned by logging methods.  Wrap slow outputs with AsyncWriter.
+//
+// Sinks are shared with loggers derived by With.
+func (l *Logger) SetSinks(sinks ...*Sink) {
+	if len(sinks) == 0 {
+		l.sinks.Store((*sinkSet)(nil))
+		return
+	}
+	ss := &sinkSet{sinks: append([]*Sink(nil), sinks...)}
+	for _, s := range sinks {
+		if s.Threshold > ss.threshold {
+			ss.threshold = s.Threshold
+		}
+	}
+	l.sinks.Store(ss)
+}
+
+// Sinks returns the sinks set by SetSinks.
+func (l *Logger) Sinks() []*Sink {
+	ss := l.sinkSet()
+	if ss == nil {
+		return nil
+	}
+	return ss.sinks
+}
+
+func (l *Logger) sinkSet() *sinkSet {
+	ss, _ := l.sinks.Load().(*sinkSet)
+	return ss
+}
+
+func (s *Sink) threshold(l *Logger) int {
+	if s.Threshold == 0 {
+		return l.Threshold()
+	}
+	return s.Threshold
+}
+
+func (s *Sink) formatter(l *Logger) Formatter {
+	if s.Formatter == nil {
+		return l.Formatter()
+	}
+	return s.Formatter
+}
+
+func (s *Sink) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Output == nil {
+		return nil
+	}
+	_, err := s.Output.Write(b)
+	if err == nil {
+		return nil
+	}
+	if s.ErrorHandler != nil {
+		err = s.ErrorHandler(err)
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("Logger.Log: %w", err)
+}
+
+func (s *Sink) flush(ctx context.Context) error {
+	if f, ok := s.Output.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// sameFormatter returns true if a and b format logs identically.
+//
+// Comparable types may still panic on comparison, for example structs
+// with interface fields holding maps.  Such formatters are treated as
+// different ones.
+func sameFormatter(a, b Formatter) (same bool) {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || !t.Comparable() {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countFormat counts the number of formatting.
+type countFormat struct {
+	JSONFormat
+	count int
+}
+
+func (f *countFormat) Format(buf []byte, l *Logger, t time.Time, severity int, msg string,
+	fields map[string]interface{}) ([]byte, error) {
+	f.count++
+	return f.JSONFormat.Format(buf, l, t, severity, msg, fields)
+}
+
+type errorWriter struct{}
+
+func (errorWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+func TestSinks(t *testing.T) {
+	t.Parallel()
+
+	f := &countFormat{}
+	l := NewLogger()
+	l.SetFormatter(f)
+	l.SetThreshold(LvInfo)
+	primary := new(bytes.Buffer)
+	l.SetOutput(primary)
+
+	debug := new(bytes.Buffer)
+	warn := new(bytes.Buffer)
+	l.SetSinks(
+		&Sink{Output: debug, Threshold: LvDebug},
+		&Sink{Output: warn, Formatter: PlainFormat{Utsname: "localhost"}, Threshold: LvWarn},
+	)
+	if len(l.Sinks()) != 2 {
+		t.Fatal("sinks should be set")
+	}
+	if !l.Enabled(LvDebug) {
+		t.Error("debug logs should be enabled for the sink")
+	}
+
+	if err := l.Debug("debug", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Warn("warn", nil); err != nil {
+		t.Fatal(err)
+	}
+	if f.count != 2 {
+		t.Errorf("logs should be formatted once for a formatter: %d", f.count)
+	}
+
+	if strings.Contains(primary.String(), "debug") || !strings.Contains(primary.String(), `"message":"warn"`) {
+		t.Errorf("unexpected primary output: %s", primary.String())
+	}
+	if !strings.Contains(debug.String(), `"message":"debug"`) || !strings.Contains(debug.String(), `"message":"warn"`) {
+		t.Errorf("unexpected debug output: %s", debug.String())
+	}
+	if strings.Contains(warn.String(), "debug") || !strings.Contains(warn.String(), `warning: "warn"`) {
+		t.Errorf("unexpected warn output: %s", warn.String())
+	}
+
+	l.SetSinks()
+	if l.Sinks() != nil || l.Enabled(LvDebug) {
+		t.Error("sinks should be removed")
+	}
+}
+
+func TestSinkError(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetFormatter(JSONFormat{})
+	l.SetOutput(errorWriter{})
+	l.SetErrorHandler(nil)
+
+	var handled error
+	buf := new(bytes.Buffer)
+	l.SetSinks(
+		&Sink{Output: errorWriter{}, ErrorHandler: func(err error) error {
+			handled = err
+			return nil
+		}},
+		&Sink{Output: errorWriter{}},
+		&Sink{Output: buf},
+	)
+
+	err := l.Info("hello", nil)
+	if err == nil || strings.Count(err.Error(), "write error") != 2 {
+		t.Errorf("errors should be joined: %v", err)
+	}
+	if handled == nil {
+		t.Error("the error handler should be called")
+	}
+	if !strings.Contains(buf.String(), "hello") {
+		t.Error("the log should be written to the healthy sink")
+	}
+}
+
+// wrapFormat is comparable but panics on comparison if Formatter
+// holds a non-comparable value.
+type wrapFormat struct {
+	Formatter
+}
+
+type mapFormat map[string]string
+
+func (f mapFormat) String() string {
+	return "map"
+}
+
+func (f mapFormat) Format(buf []byte, l *Logger, t time.Time, severity int, msg string,
+	fields map[string]interface{}) ([]byte, error) {
+	return append(buf, msg+"\n"...), nil
+}
+
+func TestSinkUncomparableFormatter(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetFormatter(wrapFormat{mapFormat{}})
+	primary := new(bytes.Buffer)
+	l.SetOutput(primary)
+	sink := new(bytes.Buffer)
+	l.SetSinks(&Sink{Output: sink, Formatter: wrapFormat{mapFormat{}}, Threshold: LvInfo})
+
+	if err := l.Info("hello", nil); err != nil {
+		t.Fatal(err)
+	}
+	if sink.String() != "hello\n" {
+		t.Errorf("unexpected sink output: %q", sink.String())
+	}
+}
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLevelCritical is the slog.Level mapped to LvCritical.
+// Levels at or above this are logged as critical.
+const SlogLevelCritical = slog.LevelError + 4
+
+// SlogSeverity returns the severity corresponding to a slog.Level.
+func SlogSeverity(level slog.Level) int {
+	switch {
+	case level >= SlogLevelCritical:
+		return LvCritical
+	case level >= slog.LevelError:
+		return LvError
+	case level >= slog.LevelWarn:
+		return LvWarn
+	case level >= slog.LevelInfo:
+		return LvInfo
+	default:
+		return LvDebug
+	}
+}
+
+// groupOrAttrs holds either a group name or a list of attributes
+// given to slog.Handler.WithGroup or slog.Handler.WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+type slogHandler struct {
+	logger *Logger
+	goas   []groupOrAttrs
+}
+
+// NewSlogHandler returns a slog.Handler that outputs records via l.
+//
+// Record levels are mapped to severities by SlogSeverity.
+// Attributes become extra fields, and groups are rendered as nested
+// map[string]interface{} values.  Top-level keys must be valid in the
+// sense of IsValidKey; otherwise Handle returns ErrInvalidKey.
+// Fields stored in the context by WithFields are added to logs.
+// The time of records is used as the time of logs if set.
+func NewSlogHandler(l *Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+// Enabled implements slog.Handler.
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.Enabled(SlogSeverity(level))
+}
+
+// WithAttrs implements slog.Handler.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements slog.Handler.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *slogHandler) with(goa groupOrAttrs) *slogHandler {
+	goas := make([]groupOrAttrs, len(h.goas)+1)
+	copy(goas, h.goas)
+	goas[len(h.goas)] = goa
+	return &slogHandler{logger: h.logger, goas: goas}
+}
+
+// Handle implements slog.Handler.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	goas := h.goas
+	if r.NumAttrs() == 0 {
+		// groups without any attributes are omitted.
+		for len(goas) > 0 && goas[len(goas)-1].group != "" {
+			goas = goas[:len(goas)-1]
+		}
+	}
+
+	fields := make(map[string]interface{})
+	cur := fields
+	top := true
+	for _, goa := range goas {
+		if goa.group != "" {
+			if top && !IsValidKey(goa.group) {
+				return ErrInvalidKey
+			}
+			m := make(map[string]interface{})
+			cur[goa.group] = m
+			cur = m
+			top = false
+			continue
+		}
+		for _, a := range goa.attrs {
+			if err := addSlogAttr(cur, a, top); err != nil {
+				return err
+			}
+		}
+	}
+
+	var err error
+	r.Attrs(func(a slog.Attr) bool {
+		err = addSlogAttr(cur, a, top)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// r.Time is zero if the record has no time; the current time is used then.
+	return h.logger.logContextAt(ctx, r.Time, SlogSeverity(r.Level), r.Message, fields)
+}
+
+// addSlogAttr adds a to m.  Keys are validated if top is true.
+func addSlogAttr(m map[string]interface{}, a slog.Attr, top bool) error {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return nil
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return nil
+		}
+		if a.Key == "" {
+			// inline the group
+			for _, ga := range attrs {
+				if err := addSlogAttr(m, ga, top); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if top && !IsValidKey(a.Key) {
+			return ErrInvalidKey
+		}
+		sub := make(map[string]interface{}, len(attrs))
+		for _, ga := range attrs {
+			if err := addSlogAttr(sub, ga, false); err != nil {
+				return err
+			}
+		}
+		m[a.Key] = sub
+		return nil
+	}
+
+	if top && !IsValidKey(a.Key) {
+		return ErrInvalidKey
+	}
+	m[a.Key] = slogValue(a.Value)
+	return nil
+}
+
+func slogValue(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindString:
+		return v.String()
+	case slog.KindTime:
+		return v.Time()
+	case slog.KindUint64:
+		return v.Uint64()
+	default:
+		return v.Any()
+	}
+}
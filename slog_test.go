@@ -0,0 +1,163 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogSeverity(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		level    slog.Level
+		expected int
+	}{
+		{slog.LevelDebug - 4, LvDebug},
+		{slog.LevelDebug, LvDebug},
+		{slog.LevelInfo, LvInfo},
+		{slog.LevelInfo + 1, LvInfo},
+		{slog.LevelWarn, LvWarn},
+		{slog.LevelError, LvError},
+		{SlogLevelCritical, LvCritical},
+		{SlogLevelCritical + 10, LvCritical},
+	}
+	for _, c := range cases {
+		if got := SlogSeverity(c.level); got != c.expected {
+			t.Errorf("SlogSeverity(%v): got %d, want %d", c.level, got, c.expected)
+		}
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("slog")
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{"localhost"})
+
+	sl := slog.New(NewSlogHandler(l))
+	sl.Debug("ignored")
+	if buf.Len() != 0 {
+		t.Error("debug log should be ignored")
+	}
+
+	sl.With("abc", 123).WithGroup("grp").With("def", "xyz").Warn("hello",
+		"num", 1.5,
+		"dur", 1500*time.Millisecond,
+		slog.Group("sub", "ok", true))
+
+	var j map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &j); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := j[FnSeverity], "warning"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := j[FnMessage], "hello"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := j["abc"], 123.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	expected := map[string]interface{}{
+		"def": "xyz",
+		"num": 1.5,
+		"dur": "1.5s",
+		"sub": map[string]interface{}{"ok": true},
+	}
+	if got := j["grp"]; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+
+	// empty groups are omitted
+	buf.Reset()
+	sl.WithGroup("empty").Error("no attrs")
+	j = nil
+	if err := json.Unmarshal(buf.Bytes(), &j); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := j["empty"]; ok {
+		t.Error(`empty group should be omitted`)
+	}
+
+	buf.Reset()
+	l.SetFormatter(PlainFormat{"localhost"})
+	sl.Log(context.Background(), SlogLevelCritical, "crit", "key", "value")
+	if !strings.Contains(buf.String(), `slog critical: "crit" key="value"`) {
+		t.Error("unexpected plain output: " + buf.String())
+	}
+}
+
+func TestSlogHandlerInvalidKey(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetOutput(new(bytes.Buffer))
+	h := NewSlogHandler(l)
+
+	r := slog.NewRecord(time.Now(), slog.LevelError, "msg", 0)
+	r.AddAttrs(slog.String("Invalid-Key", "value"))
+	if err := h.Handle(context.Background(), r); err != ErrInvalidKey {
+		t.Errorf("got %v, want ErrInvalidKey", err)
+	}
+
+	r = slog.NewRecord(time.Now(), slog.LevelError, "msg", 0)
+	r.AddAttrs(slog.String(FnMessage, "value"))
+	if err := h.Handle(context.Background(), r); err != ErrInvalidKey {
+		t.Errorf("got %v, want ErrInvalidKey", err)
+	}
+
+	// nested keys are not restricted
+	r = slog.NewRecord(time.Now(), slog.LevelError, "msg", 0)
+	r.AddAttrs(slog.String("Nested Key", "value"))
+	if err := h.WithGroup("grp").Handle(context.Background(), r); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSlogHandlerTime(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	buf := new(bytes.Buffer)
+	l.SetOutput(buf)
+	l.SetFormatter(JSONFormat{})
+	h := NewSlogHandler(l)
+
+	loggedAt := time.Date(2001, 12, 3, 13, 45, 1, 123456000, time.UTC)
+	if err := h.Handle(context.Background(), slog.NewRecord(loggedAt, slog.LevelInfo, "old", 0)); err != nil {
+		t.Fatal(err)
+	}
+	var j map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &j); err != nil {
+		t.Fatal(err)
+	}
+	if j[FnLoggedAt] != "2001-12-03T13:45:01.123456Z" {
+		t.Errorf("record time is not used: %v", j[FnLoggedAt])
+	}
+
+	// zero time means the current time.
+	buf.Reset()
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "now", 0)); err != nil {
+		t.Fatal(err)
+	}
+	j = nil
+	if err := json.Unmarshal(buf.Bytes(), &j); err != nil {
+		t.Fatal(err)
+	}
+	at, err := time.Parse(time.RFC3339Nano, j[FnLoggedAt].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(at) > time.Minute {
+		t.Errorf("current time is not used: %v", at)
+	}
+}
@@ -0,0 +1,298 @@
+package log
+
+import (
+	"encoding"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// defaultSyslogFacility is the facility for user-level messages.
+	defaultSyslogFacility = 1
+
+	// defaultSyslogSDID is the SD-ID for extra fields.
+	// 32473 is the private enterprise number reserved for documentation.
+	defaultSyslogSDID = "fields@32473"
+
+	maxSyslogAppName = 48
+	maxSyslogTag     = 32
+	maxSyslogSDName  = 32
+)
+
+// SyslogFormat implements Formatter for syslog messages.
+//
+// By default, messages are formatted as defined in RFC 5424.
+// The topic is used as APP-NAME, and extra fields are put in a
+// structured data element.  If RFC3164 is true, messages are formatted
+// in the legacy BSD syslog format with extra fields appended to MSG
+// in the same way as PlainFormat.
+//
+// Use NewSyslogWriter to send formatted messages to syslog daemons.
+type SyslogFormat struct {
+	// Utsname can normally be left blank.
+	// If not empty, the string is used instead of the hostname.
+	// Utsname must match this regexp: ^[a-z][a-z0-9-]*$
+	Utsname string
+
+	// Facility is the syslog facility code such as 16 for local0.
+	// If zero, 1 (user-level messages) is used.
+	Facility int
+
+	// RFC3164 selects the BSD syslog format.
+	RFC3164 bool
+
+	// SDID is the SD-ID of the structured data element for extra fields.
+	// If empty, "fields@32473" is used.
+	SDID string
+}
+
+// String returns "syslog".
+func (f SyslogFormat) String() string {
+	return "syslog"
+}
+
+// Format implements Formatter.Format.
+func (f SyslogFormat) Format(buf []byte, l *Logger, t time.Time, severity int,
+	msg string, fields map[string]interface{}) ([]byte, error) {
+	var err error
+
+	facility := f.Facility
+	if facility == 0 {
+		facility = defaultSyslogFacility
+	}
+	switch {
+	case severity < 0:
+		severity = 0
+	case severity > LvDebug:
+		severity = LvDebug
+	}
+	hostname := utsname
+	if len(f.Utsname) > 0 {
+		hostname = f.Utsname
+	}
+
+	keys, values, err := mergeFields(l, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(facility*8+severity), 10)
+	buf = append(buf, '>')
+
+	if f.RFC3164 {
+		buf = t.Local().AppendFormat(buf, time.Stamp)
+		buf = append(buf, ' ')
+		buf = append(buf, hostname...)
+		buf = append(buf, ' ')
+		buf = append(buf, truncate(l.Topic(), maxSyslogTag)...)
+		buf = append(buf, '[')
+		buf = strconv.AppendInt(buf, int64(os.Getpid()), 10)
+		buf = append(buf, "]: "...)
+		buf = append(buf, strings.ToValidUTF8(msg, string(utf8.RuneError))...)
+		for i, k := range keys {
+			buf = append(buf, ' ')
+			buf = append(buf, k...)
+			buf = append(buf, '=')
+			buf, err = appendPlain(buf, values[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '\n'), nil
+	}
+
+	buf = append(buf, "1 "...)
+	buf = t.UTC().AppendFormat(buf, RFC3339Micro)
+	buf = append(buf, ' ')
+	buf = append(buf, hostname...)
+	buf = append(buf, ' ')
+	buf = append(buf, truncate(l.Topic(), maxSyslogAppName)...)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(os.Getpid()), 10)
+	buf = append(buf, " - "...)
+
+	if len(keys) == 0 {
+		buf = append(buf, '-')
+	} else {
+		sdid := f.SDID
+		if len(sdid) == 0 {
+			sdid = defaultSyslogSDID
+		}
+		buf = append(buf, '[')
+		buf = append(buf, sdid...)
+		for i, k := range keys {
+			buf = append(buf, ' ')
+			buf = append(buf, truncate(k, maxSyslogSDName)...)
+			buf = append(buf, `="`...)
+			buf, err = appendSDParam(buf, values[i])
+			if err != nil {
+				return nil, err
+			}
+			buf = append(buf, '"')
+		}
+		buf = append(buf, ']')
+	}
+
+	buf = append(buf, ' ')
+	buf = append(buf, strings.ToValidUTF8(msg, string(utf8.RuneError))...)
+	return append(buf, '\n'), nil
+}
+
+// mergeFields merges fields and defaults of l, and returns keys sorted
+// in alphabetical order with corresponding values.
+func mergeFields(l *Logger, fields map[string]interface{}) ([]string, []interface{}, error) {
+	defaults := l.Defaults()
+	keys := make([]string, 0, len(fields)+len(defaults))
+	for k := range fields {
+		if !IsValidKey(k) {
+			return nil, nil, ErrInvalidKey
+		}
+		keys = append(keys, k)
+	}
+	for k := range defaults {
+		if _, ok := fields[k]; ok {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		if v, ok := fields[k]; ok {
+			values[i] = v
+		} else {
+			values[i] = defaults[k]
+		}
+	}
+	return keys, values, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// appendSDParam appends v as PARAM-VALUE of RFC 5424 structured data.
+func appendSDParam(buf []byte, v interface{}) ([]byte, error) {
+	start := len(buf)
+	buf, err := appendText(buf, v)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for _, b := range buf[start:] {
+		if b == '"' || b == '\\' || b == ']' {
+			n++
+		}
+	}
+	if n == 0 {
+		return buf, nil
+	}
+
+	s := string(buf[start:])
+	buf = buf[:start]
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\\', ']':
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, s[i])
+	}
+	return buf, nil
+}
+
+// appendText appends v as a text without quotation.
+// Strings are appended as they are, and other values are formatted as JSON.
+func appendText(buf []byte, v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		return append(buf, strings.ToValidUTF8(t, string(utf8.RuneError))...), nil
+	case time.Time:
+		return t.UTC().AppendFormat(buf, RFC3339Micro), nil
+	case encoding.TextMarshaler:
+		s, err := t.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, strings.ToValidUTF8(string(s), string(utf8.RuneError))...), nil
+	case error:
+		return append(buf, strings.ToValidUTF8(t.Error(), string(utf8.RuneError))...), nil
+	}
+	return appendJSON(buf, v)
+}
+
+type syslogWriter struct {
+	*netWriter
+	octetCounting bool
+}
+
+// NewSyslogWriter connects to a syslog daemon and returns an io.WriteCloser
+// to send logs formatted by SyslogFormat.
+//
+// network and addr are passed to net.Dial.  If network is empty,
+// the local syslog daemon is used via a unix domain socket.
+//
+// For stream networks ("tcp", "tcp4", "tcp6", and "unix"), messages are
+// framed by octet-counting described in RFC 6587.  For other networks,
+// each message is sent as a datagram.
+//
+// If sending fails, the writer reconnects to the daemon and retries once.
+func NewSyslogWriter(network, addr string) (io.WriteCloser, error) {
+	if len(network) == 0 {
+		return dialLocalSyslog()
+	}
+
+	nw, err := dialNetWriter(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	w := &syslogWriter{netWriter: nw}
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		w.octetCounting = true
+	}
+	return w, nil
+}
+
+func dialLocalSyslog() (io.WriteCloser, error) {
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+			w, err := NewSyslogWriter(network, path)
+			if err == nil {
+				return w, nil
+			}
+		}
+	}
+	return nil, errors.New("local syslog daemon is not available")
+}
+
+// Write sends p as a syslog message.  A trailing newline is removed.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	msg := p
+	if len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+	}
+
+	if w.octetCounting {
+		b := make([]byte, 0, len(msg)+8)
+		b = strconv.AppendInt(b, int64(len(msg)), 10)
+		b = append(b, ' ')
+		msg = append(b, msg...)
+	}
+
+	if _, err := w.netWriter.Write(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,174 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogFormat(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.SetTopic("app")
+	l.SetDefaults(map[string]interface{}{
+		"abc": 123,
+	})
+	ts := time.Date(2001, time.December, 3, 13, 45, 1, 123456789, time.UTC)
+	buf := make([]byte, 0, 4096)
+
+	f := SyslogFormat{Utsname: "localhost"}
+	b, err := f.Format(buf, l, ts, LvError, "hello", map[string]interface{}{
+		"def": `a"b]c\d`,
+		"ghi": []int{1, 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf(`<11>1 2001-12-03T13:45:01.123456Z localhost app %d - [fields@32473 abc="123" def="a\"b\]c\\d" ghi="[1,2\]"] hello`+"\n", os.Getpid())
+	if string(b) != expected {
+		t.Errorf("got %q, want %q", b, expected)
+	}
+
+	l.SetDefaults(nil)
+	f = SyslogFormat{Utsname: "localhost", Facility: 16, SDID: "test@1"}
+	b, err = f.Format(buf, l, ts, LvDebug, "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = fmt.Sprintf("<135>1 2001-12-03T13:45:01.123456Z localhost app %d - - hello\n", os.Getpid())
+	if string(b) != expected {
+		t.Errorf("got %q, want %q", b, expected)
+	}
+
+	f = SyslogFormat{Utsname: "localhost", RFC3164: true}
+	b, err = f.Format(buf, l, ts, LvWarn, "hello", map[string]interface{}{
+		"def": "xyz",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = fmt.Sprintf(`<12>%s localhost app[%d]: hello def="xyz"`+"\n", ts.Local().Format(time.Stamp), os.Getpid())
+	if string(b) != expected {
+		t.Errorf("got %q, want %q", b, expected)
+	}
+
+	if _, err := f.Format(buf, l, ts, LvWarn, "hello", map[string]interface{}{
+		FnMessage: "xyz",
+	}); err != ErrInvalidKey {
+		t.Errorf("got %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestSyslogWriterUnixgram(t *testing.T) {
+	t.Parallel()
+
+	sock := filepath.Join(t.TempDir(), "log.sock")
+	listen := func() *net.UnixConn {
+		c, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	read := func(c *net.UnixConn) string {
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 4096)
+		n, err := c.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b[:n])
+	}
+
+	server := listen()
+	w, err := NewSyslogWriter("unixgram", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	l := NewLogger()
+	l.SetTopic("app")
+	l.SetOutput(w)
+	l.SetFormatter(SyslogFormat{})
+	if err := l.Info("first", nil); err != nil {
+		t.Fatal(err)
+	}
+	msg := read(server)
+	if !strings.HasPrefix(msg, "<14>1 ") || !strings.HasSuffix(msg, " app "+strconv.Itoa(os.Getpid())+" - - first") {
+		t.Errorf("unexpected message: %q", msg)
+	}
+
+	// restart the daemon
+	server.Close()
+	os.Remove(sock)
+	server = listen()
+	defer server.Close()
+
+	if err := l.Info("second", nil); err != nil {
+		t.Fatal(err)
+	}
+	msg = read(server)
+	if !strings.HasSuffix(msg, " - - second") {
+		t.Errorf("unexpected message: %q", msg)
+	}
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w, err := NewSyslogWriter("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	l := NewLogger()
+	l.SetOutput(w)
+	l.SetFormatter(SyslogFormat{})
+	l.Error("hello world", nil)
+	l.Error("second\nline", nil)
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(c)
+
+	for _, expected := range []string{"hello world", "second\nline"} {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(string(msg), " - - "+expected) {
+			t.Errorf("unexpected message: %q", msg)
+		}
+	}
+}